@@ -0,0 +1,100 @@
+package cosmwasm
+
+import (
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/CosmWasm/go-cosmwasm/types"
+)
+
+// ibcEntryPoints are the exports a contract needs to take part in IBC
+var ibcEntryPoints = []string{
+	"ibc_channel_open",
+	"ibc_channel_connect",
+	"ibc_channel_close",
+	"ibc_packet_receive",
+	"ibc_packet_ack",
+	"ibc_packet_timeout",
+}
+
+// requiresPrefix marks exports or custom sections declaring a feature the contract needs, e.g. requires_staking
+const requiresPrefix = "requires_"
+
+// versionPrefixes mark exports declaring the interface version of a contract.
+// cosmwasm_vm_version_N is used up to CosmWasm 0.13, interface_version_N afterwards.
+var versionPrefixes = []string{"cosmwasm_vm_version_", "interface_version_"}
+
+// interfaceVersion returns the highest interface version declared by the module, or 0 if there is none
+func interfaceVersion(module *wasmModule) uint32 {
+	var version uint32
+	for _, e := range module.exports {
+		if e.Kind != externFunc {
+			continue
+		}
+		for _, prefix := range versionPrefixes {
+			if !strings.HasPrefix(e.Name, prefix) {
+				continue
+			}
+			v, err := strconv.ParseUint(strings.TrimPrefix(e.Name, prefix), 10, 32)
+			if err == nil && uint32(v) > version {
+				version = uint32(v)
+			}
+		}
+	}
+	return version
+}
+
+// analyzeModule builds the analysis report from the exports of the module
+func analyzeModule(module *wasmModule) types.AnalysisReport {
+	hasIBC := true
+	for _, name := range ibcEntryPoints {
+		if !module.hasExport(name, externFunc) {
+			hasIBC = false
+			break
+		}
+	}
+
+	required := make(map[string]bool)
+	for _, e := range module.exports {
+		if e.Kind == externFunc && strings.HasPrefix(e.Name, requiresPrefix) && len(e.Name) > len(requiresPrefix) {
+			required[strings.TrimPrefix(e.Name, requiresPrefix)] = true
+		}
+	}
+	// newer toolchains declare them as custom sections instead
+	for _, name := range module.customSections {
+		if strings.HasPrefix(name, requiresPrefix) && len(name) > len(requiresPrefix) {
+			required[strings.TrimPrefix(name, requiresPrefix)] = true
+		}
+	}
+	var features []string
+	for f := range required {
+		features = append(features, f)
+	}
+	sort.Strings(features)
+
+	return types.AnalysisReport{
+		HasIBCEntryPoints:    hasIBC,
+		HasMigrateEntryPoint: module.hasExport("migrate", externFunc),
+		RequiredFeatures:     strings.Join(features, ","),
+		RequiredFeaturesSet:  features,
+		InterfaceVersion:     interfaceVersion(module),
+	}
+}
+
+// exportedFunctions describes all functions exported by the module, in export order
+func exportedFunctions(module *wasmModule) ([]types.FunctionSignature, error) {
+	var res []types.FunctionSignature
+	for _, e := range module.exports {
+		if e.Kind != externFunc {
+			continue
+		}
+		ft, err := module.funcType(e.Index)
+		if err != nil {
+			return nil, fmt.Errorf("export %s: %w", e.Name, err)
+		}
+		res = append(res, types.FunctionSignature{Name: e.Name, Params: ft.Params, Results: ft.Results})
+	}
+	return res, nil
+}
@@ -0,0 +1,80 @@
+package cosmwasm
+
+import (
+	"io/ioutil"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/CosmWasm/go-cosmwasm/types"
+)
+
+func TestInterfaceVersion(t *testing.T) {
+	cases := map[string]struct {
+		exports []string
+		version uint32
+	}{
+		"none":         {[]string{"allocate", "query"}, 0},
+		"legacy":       {[]string{"cosmwasm_vm_version_3"}, 3},
+		"new":          {[]string{"interface_version_8"}, 8},
+		"highest wins": {[]string{"interface_version_7", "interface_version_8"}, 8},
+		"not a number": {[]string{"interface_version_x"}, 0},
+	}
+	for name, tc := range cases {
+		t.Run(name, func(t *testing.T) {
+			module, err := parseWasm(buildWasm(exportSection(true, tc.exports...)))
+			require.NoError(t, err)
+			assert.Equal(t, tc.version, interfaceVersion(module))
+		})
+	}
+}
+
+// customSection creates a custom section with the given name and payload
+func customSection(name string, payload ...byte) []byte {
+	s := []byte{sectionCustom, byte(len(name))}
+	s = append(s, name...)
+	return append(s, payload...)
+}
+
+func TestRequiredFeatures(t *testing.T) {
+	code := buildWasm(
+		customSection("requires_iterator"),
+		exportSection(true, "requires_staking", "requires_iterator"),
+		customSection("requires_stargate", 1, 2, 3),
+		customSection("name"),
+	)
+	module, err := parseWasm(code)
+	require.NoError(t, err)
+	assert.Equal(t, []string{"requires_iterator", "requires_stargate", "name"}, module.customSections)
+	report := analyzeModule(module)
+	assert.Equal(t, "iterator,staking,stargate", report.RequiredFeatures)
+	assert.Equal(t, []string{"iterator", "staking", "stargate"}, report.RequiredFeaturesSet)
+
+	module, err = parseWasm(buildWasm(exportSection(true, "allocate")))
+	require.NoError(t, err)
+	report = analyzeModule(module)
+	assert.Equal(t, "", report.RequiredFeatures)
+	assert.Nil(t, report.RequiredFeaturesSet)
+}
+
+func TestExportedFunctions(t *testing.T) {
+	code, err := ioutil.ReadFile("./api/testdata/hackatom.wasm")
+	require.NoError(t, err)
+	module, err := parseWasm(code)
+	require.NoError(t, err)
+
+	sigs, err := exportedFunctions(module)
+	require.NoError(t, err)
+	byName := make(map[string]types.FunctionSignature)
+	for _, sig := range sigs {
+		byName[sig.Name] = sig
+	}
+	assert.Equal(t, types.FunctionSignature{Name: "allocate", Params: []string{"i32"}, Results: []string{"i32"}}, byName["allocate"])
+	assert.Equal(t, types.FunctionSignature{Name: "deallocate", Params: []string{"i32"}, Results: []string{}}, byName["deallocate"])
+	assert.Equal(t, types.FunctionSignature{Name: "init", Params: []string{"i32", "i32"}, Results: []string{"i32"}}, byName["init"])
+	assert.Equal(t, types.FunctionSignature{Name: "query", Params: []string{"i32"}, Results: []string{"i32"}}, byName["query"])
+	// memory and globals are not functions
+	_, ok := byName["memory"]
+	assert.False(t, ok)
+}
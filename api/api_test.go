@@ -92,3 +92,45 @@ func TestHumanAddressFailure(t *testing.T) {
 	// with this message
 	require.Equal(t, resp.Err.Error(), "generic: humanize_address errored: mock failure - human_address")
 }
+
+func TestHumanAddressInvalidUTF8(t *testing.T) {
+	cache, cleanup := withCache(t)
+	defer cleanup()
+
+	// create contract
+	wasm, err := ioutil.ReadFile("./testdata/hackatom.wasm")
+	require.NoError(t, err)
+	id, err := Create(cache, wasm)
+	require.NoError(t, err)
+
+	gasMeter := NewMockGasMeter(100000000)
+	// instantiate it with this store
+	store := NewLookup(gasMeter)
+	api := NewMockAPI()
+	querier := DefaultQuerier(mockContractAddr, types.Coins{types.NewCoin(100, "ATOM")})
+	params, err := json.Marshal(mockEnv("creator"))
+	require.NoError(t, err)
+
+	// instantiate it normally
+	msg := []byte(`{"verifier": "short", "beneficiary": "bob"}`)
+	igasMeter := GasMeter(gasMeter)
+	_, _, err = Instantiate(cache, id, params, msg, &igasMeter, store, api, &querier, 100000000)
+	require.NoError(t, err)
+
+	// call query which will call humanize address, getting invalid utf-8 back
+	badApi := NewMockInvalidUTF8API()
+	gasMeter3 := NewMockGasMeter(100000000)
+	query := []byte(`{"verifier":{}}`)
+	igasMeter3 := GasMeter(gasMeter3)
+	res, _, err := Query(cache, id, query, &igasMeter3, store, badApi, &querier, 100000000)
+	require.NoError(t, err)
+	var resp types.QueryResponse
+	err = json.Unmarshal(res, &resp)
+	require.NoError(t, err)
+
+	// the contract gets an error instead of the invalid string
+	require.Nil(t, resp.Ok)
+	require.NotNil(t, resp.Err)
+	require.NotNil(t, resp.Err.GenericErr)
+	require.Contains(t, resp.Err.Error(), "humanize_address returned invalid utf-8")
+}
@@ -37,6 +37,7 @@ import (
 	"fmt"
 	"log"
 	"reflect"
+	"unicode/utf8"
 	"unsafe"
 
 	dbm "github.com/tendermint/tm-db"
@@ -116,6 +117,8 @@ type DBState struct {
 	Store KVStore
 	// IteratorStackID is used to lookup the proper stack frame for iterators associated with this DB (iterator.go)
 	IteratorStackID uint64
+	// ReadOnly rejects all writes and removes coming from the contract (used for queries)
+	ReadOnly bool
 }
 
 // use this to create C.DB in two steps, so the pointer lives as long as the calling stack
@@ -129,6 +132,14 @@ func buildDBState(kv KVStore, counter uint64) DBState {
 	}
 }
 
+// buildQueryDBState is buildDBState for queries, which must never mutate state.
+// Writes and removes are rejected by the callbacks before they reach the store.
+func buildQueryDBState(kv KVStore, counter uint64) DBState {
+	state := buildDBState(kv, counter)
+	state.ReadOnly = true
+	return state
+}
+
 // contract: original pointer/struct referenced must live longer than C.DB struct
 // since this is only used internally, we can verify the code that this is the case
 func buildDB(state *DBState, gm *GasMeter) C.DB {
@@ -190,8 +201,16 @@ func cSet(ptr *C.db_t, gasMeter *C.gas_meter_t, usedGas *C.uint64_t, key C.Buffe
 		return C.GoResult_BadArgument
 	}
 
+	state := (*DBState)(unsafe.Pointer(ptr))
+	if state.ReadOnly {
+		if errOut != nil {
+			*errOut = allocateRust([]byte("db_write not allowed in query"))
+		}
+		return C.GoResult_User
+	}
+
 	gm := *(*GasMeter)(unsafe.Pointer(gasMeter))
-	kv := *(*KVStore)(unsafe.Pointer(ptr))
+	kv := state.Store
 	k := receiveSlice(key)
 	v := receiveSlice(val)
 
@@ -211,8 +230,16 @@ func cDelete(ptr *C.db_t, gasMeter *C.gas_meter_t, usedGas *C.uint64_t, key C.Bu
 		return C.GoResult_BadArgument
 	}
 
+	state := (*DBState)(unsafe.Pointer(ptr))
+	if state.ReadOnly {
+		if errOut != nil {
+			*errOut = allocateRust([]byte("db_remove not allowed in query"))
+		}
+		return C.GoResult_User
+	}
+
 	gm := *(*GasMeter)(unsafe.Pointer(gasMeter))
-	kv := *(*KVStore)(unsafe.Pointer(ptr))
+	kv := state.Store
 	k := receiveSlice(key)
 
 	gasBefore := gm.GasConsumed()
@@ -223,6 +250,42 @@ func cDelete(ptr *C.db_t, gasMeter *C.gas_meter_t, usedGas *C.uint64_t, key C.Bu
 	return C.GoResult_Ok
 }
 
+// callDBWrite and callDBRemove call the db_write and db_remove callbacks like the VM does and return the
+// error they report. They let the tests of this package cover the callbacks, as tests cannot use cgo.
+func callDBWrite(state *DBState, gasMeter *GasMeter, key, value []byte) error {
+	k := sendSlice(key)
+	defer freeAfterSend(k)
+	v := sendSlice(value)
+	defer freeAfterSend(v)
+	db := buildDB(state, gasMeter)
+	var usedGas C.uint64_t
+	errOut := C.Buffer{}
+	res := cSet(db.state, db.gas_meter, &usedGas, k, v, &errOut)
+	return callbackError(res, errOut)
+}
+
+func callDBRemove(state *DBState, gasMeter *GasMeter, key []byte) error {
+	k := sendSlice(key)
+	defer freeAfterSend(k)
+	db := buildDB(state, gasMeter)
+	var usedGas C.uint64_t
+	errOut := C.Buffer{}
+	res := cDelete(db.state, db.gas_meter, &usedGas, k, &errOut)
+	return callbackError(res, errOut)
+}
+
+func callbackError(res C.GoResult, errOut C.Buffer) error {
+	msg := receiveVector(errOut)
+	switch res {
+	case C.GoResult_Ok:
+		return nil
+	case C.GoResult_User:
+		return fmt.Errorf("%s", msg)
+	default:
+		return fmt.Errorf("callback failed with result %d: %s", res, msg)
+	}
+}
+
 //export cScan
 func cScan(ptr *C.db_t, gasMeter *C.gas_meter_t, usedGas *C.uint64_t, start C.Buffer, end C.Buffer, order i32, out *C.GoIter, errOut *C.Buffer) (ret C.GoResult) {
 	defer recoverPanic(&ret)
@@ -341,6 +404,11 @@ func cHumanAddress(ptr *C.api_t, canon C.Buffer, human *C.Buffer, errOut *C.Buff
 	if len(h) == 0 {
 		panic(fmt.Sprintf("`api.HumanAddress()` returned an empty string for %q", c))
 	}
+	if !utf8.ValidString(h) {
+		// the contract reads this as a string, so do not hand it invalid bytes
+		*errOut = allocateRust([]byte(fmt.Sprintf("humanize_address returned invalid utf-8 for %q", c)))
+		return C.GoResult_User
+	}
 	*human = allocateRust([]byte(h))
 	return C.GoResult_Ok
 }
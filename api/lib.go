@@ -5,7 +5,9 @@ package api
 import "C"
 
 import (
+	"bytes"
 	"fmt"
+	"io"
 	"syscall"
 
 	"github.com/CosmWasm/go-cosmwasm/types"
@@ -26,14 +28,35 @@ type Cache struct {
 
 type Querier = types.Querier
 
+// CacheOptions are the options of the cache of the VM. All other options of the VMConfig are
+// enforced in Go and do not affect the cache.
+type CacheOptions struct {
+	// DataDir is the directory where the raw wasm and the pre-compile cache are stored
+	DataDir string
+	// SupportedFeatures is a comma separated list of features the chain supports, e.g. "staking"
+	SupportedFeatures string
+	// CacheSize sets the size of the in-memory LRU cache for prepared VMs
+	CacheSize uint64
+}
+
+// InitCache is InitCacheWithOptions with the options given one by one
 func InitCache(dataDir string, supportedFeatures string, cacheSize uint64) (Cache, error) {
-	dir := sendSlice([]byte(dataDir))
+	return InitCacheWithOptions(CacheOptions{
+		DataDir:           dataDir,
+		SupportedFeatures: supportedFeatures,
+		CacheSize:         cacheSize,
+	})
+}
+
+// InitCacheWithOptions creates the cache of the VM
+func InitCacheWithOptions(opts CacheOptions) (Cache, error) {
+	dir := sendSlice([]byte(opts.DataDir))
 	defer freeAfterSend(dir)
-	features := sendSlice([]byte(supportedFeatures))
+	features := sendSlice([]byte(opts.SupportedFeatures))
 	defer freeAfterSend(features)
 	errmsg := C.Buffer{}
 
-	ptr, err := C.init_cache(dir, features, usize(cacheSize), &errmsg)
+	ptr, err := C.init_cache(dir, features, usize(opts.CacheSize), &errmsg)
 	if err != nil {
 		return Cache{}, errorWithMessage(err, errmsg)
 	}
@@ -66,6 +89,30 @@ func GetCode(cache Cache, code_id []byte) ([]byte, error) {
 	return receiveVector(code), nil
 }
 
+// ReadCode calls fn with the wasm code for the given id. The code is not copied into go memory,
+// so fn must not hold on to the slice after it returns.
+func ReadCode(cache Cache, code_id []byte, fn func(code []byte) error) error {
+	id := sendSlice(code_id)
+	defer freeAfterSend(id)
+	errmsg := C.Buffer{}
+	code, err := C.get_code(cache.ptr, id, &errmsg)
+	if err != nil {
+		return errorWithMessage(err, errmsg)
+	}
+	defer freeVector(code)
+	return fn(viewVector(code))
+}
+
+func WriteCodeTo(cache Cache, code_id []byte, w io.Writer) (int, error) {
+	var n int
+	err := ReadCode(cache, code_id, func(code []byte) (err error) {
+		// write straight from the rust owned memory, so we never copy the code into go memory
+		n, err = w.Write(code)
+		return err
+	})
+	return n, err
+}
+
 func Instantiate(
 	cache Cache,
 	code_id []byte,
@@ -98,7 +145,7 @@ func Instantiate(
 	res, err := C.instantiate(cache.ptr, id, p, m, db, a, q, u64(gasLimit), &gasUsed, &errmsg)
 	if err != nil && err.(syscall.Errno) != C.ErrnoValue_Success {
 		// Depending on the nature of the error, `gasUsed` will either have a meaningful value, or just 0.
-		return nil, uint64(gasUsed), errorWithMessage(err, errmsg)
+		return nil, uint64(gasUsed), callErrorWithMessage(err, errmsg, gasUsed, gasLimit)
 	}
 	return receiveVector(res), uint64(gasUsed), nil
 }
@@ -135,7 +182,7 @@ func Handle(
 	res, err := C.handle(cache.ptr, id, p, m, db, a, q, u64(gasLimit), &gasUsed, &errmsg)
 	if err != nil && err.(syscall.Errno) != C.ErrnoValue_Success {
 		// Depending on the nature of the error, `gasUsed` will either have a meaningful value, or just 0.
-		return nil, uint64(gasUsed), errorWithMessage(err, errmsg)
+		return nil, uint64(gasUsed), callErrorWithMessage(err, errmsg, gasUsed, gasLimit)
 	}
 	return receiveVector(res), uint64(gasUsed), nil
 }
@@ -172,7 +219,7 @@ func Migrate(
 	res, err := C.migrate(cache.ptr, id, p, m, db, a, q, u64(gasLimit), &gasUsed, &errmsg)
 	if err != nil && err.(syscall.Errno) != C.ErrnoValue_Success {
 		// Depending on the nature of the error, `gasUsed` will either have a meaningful value, or just 0.
-		return nil, uint64(gasUsed), errorWithMessage(err, errmsg)
+		return nil, uint64(gasUsed), callErrorWithMessage(err, errmsg, gasUsed, gasLimit)
 	}
 	return receiveVector(res), uint64(gasUsed), nil
 }
@@ -196,7 +243,7 @@ func Query(
 	counter := startContract()
 	defer endContract(counter)
 
-	dbState := buildDBState(store, counter)
+	dbState := buildQueryDBState(store, counter)
 	db := buildDB(&dbState, gasMeter)
 	a := buildAPI(api)
 	q := buildQuerier(querier)
@@ -206,7 +253,7 @@ func Query(
 	res, err := C.query(cache.ptr, id, m, db, a, q, u64(gasLimit), &gasUsed, &errmsg)
 	if err != nil && err.(syscall.Errno) != C.ErrnoValue_Success {
 		// Depending on the nature of the error, `gasUsed` will either have a meaningful value, or just 0.
-		return nil, uint64(gasUsed), errorWithMessage(err, errmsg)
+		return nil, uint64(gasUsed), callErrorWithMessage(err, errmsg, gasUsed, gasLimit)
 	}
 	return receiveVector(res), uint64(gasUsed), nil
 }
@@ -222,5 +269,30 @@ func errorWithMessage(err error, b C.Buffer) error {
 	if msg == nil {
 		return err
 	}
+	// the VM reports unknown code ids as a failure to open the wasm file
+	if bytes.Contains(msg, codeNotFoundMessage) {
+		return fmt.Errorf("%w: %s", types.ErrCodeNotFound, string(msg))
+	}
 	return fmt.Errorf("%s", string(msg))
 }
+
+// codeNotFoundMessage is part of the error message of the VM when there is no code for a code id.
+// The VM has no distinct error code for this. The message comes from load in src/wasm_store.rs
+// of cosmwasm-vm 0.10.0, which fails with a VmError::CacheErr when the file of the code id cannot
+// be opened. TestGetCodeNotFound pins it, so an update of the VM changing the message is caught.
+var codeNotFoundMessage = []byte("Error opening Wasm file for reading")
+
+// callErrorWithMessage is errorWithMessage for contract calls, where we can tell apart
+// running out of gas inside the VM from running out of gas in a host callback
+func callErrorWithMessage(err error, b C.Buffer, gasUsed u64, gasLimit uint64) error {
+	err = errorWithMessage(err, b)
+	if err != (types.OutOfGasError{}) {
+		return err
+	}
+	// The VM only stops at its gas limit, so anything less means a callback ran out of gas on the sdk meter.
+	// This is a heuristic, see types.ErrOutOfGasHost.
+	if uint64(gasUsed) < gasLimit {
+		return types.ErrOutOfGasHost
+	}
+	return types.ErrOutOfGasWasm
+}
@@ -1,7 +1,9 @@
 package api
 
 import (
+	"bytes"
 	"encoding/json"
+	"errors"
 	"io/ioutil"
 	"os"
 	"testing"
@@ -29,6 +31,21 @@ func TestInitAndReleaseCache(t *testing.T) {
 	ReleaseCache(cache)
 }
 
+func TestInitCacheWithOptions(t *testing.T) {
+	tmpdir, err := ioutil.TempDir("", "go-cosmwasm")
+	require.NoError(t, err)
+	defer os.RemoveAll(tmpdir)
+
+	cache, err := InitCacheWithOptions(CacheOptions{DataDir: tmpdir, SupportedFeatures: DEFAULT_FEATURES, CacheSize: 3})
+	require.NoError(t, err)
+	defer ReleaseCache(cache)
+
+	wasm, err := ioutil.ReadFile("./testdata/hackatom.wasm")
+	require.NoError(t, err)
+	_, err = Create(cache, wasm)
+	require.NoError(t, err)
+}
+
 func withCache(t *testing.T) (Cache, func()) {
 	tmpdir, err := ioutil.TempDir("", "go-cosmwasm")
 	require.NoError(t, err)
@@ -57,6 +74,38 @@ func TestCreateAndGet(t *testing.T) {
 	require.Equal(t, wasm, code)
 }
 
+func TestGetCodeNotFound(t *testing.T) {
+	cache, cleanup := withCache(t)
+	defer cleanup()
+
+	// ErrCodeNotFound is detected by the message of the VM, so this breaks if it changes
+	_, err := GetCode(cache, bytes.Repeat([]byte{0xab}, 32))
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "Error opening Wasm file for reading")
+	assert.True(t, errors.Is(err, types.ErrCodeNotFound))
+}
+
+func TestWriteCodeTo(t *testing.T) {
+	cache, cleanup := withCache(t)
+	defer cleanup()
+
+	wasm, err := ioutil.ReadFile("./testdata/hackatom.wasm")
+	require.NoError(t, err)
+
+	id, err := Create(cache, wasm)
+	require.NoError(t, err)
+
+	var buf bytes.Buffer
+	n, err := WriteCodeTo(cache, id, &buf)
+	require.NoError(t, err)
+	require.Equal(t, len(wasm), n)
+	require.Equal(t, wasm, buf.Bytes())
+
+	// unknown code ids are reported as errors
+	_, err = WriteCodeTo(cache, []byte("foobar"), &buf)
+	require.Error(t, err)
+}
+
 func TestCreateFailsWithBadData(t *testing.T) {
 	cache, cleanup := withCache(t)
 	defer cleanup()
@@ -118,6 +167,8 @@ func TestInstantiate(t *testing.T) {
 	require.NoError(t, err)
 	require.Nil(t, resp.Err)
 	require.Equal(t, 0, len(resp.Ok.Messages))
+	// hackatom does not set any data on init
+	require.Nil(t, resp.Ok.Data)
 }
 
 func TestHandle(t *testing.T) {
@@ -212,6 +263,10 @@ func TestHandleCpuLoop(t *testing.T) {
 	res, cost, err = Handle(cache, id, params, []byte(`{"cpu_loop":{}}`), &igasMeter2, store, api, &querier, maxGas)
 	diff = time.Now().Sub(start)
 	require.Error(t, err)
+	assert.Equal(t, types.ErrOutOfGasWasm, err)
+	assert.IsType(t, types.OutOfGasWasmError{}, err)
+	assert.True(t, errors.Is(err, types.OutOfGasError{}))
+	assert.False(t, errors.Is(err, types.ErrOutOfGasHost))
 	assert.Equal(t, cost, maxGas)
 	t.Logf("CPULoop Time (%d gas): %s\n", cost, diff)
 }
@@ -257,6 +312,41 @@ func TestHandleStorageLoop(t *testing.T) {
 	require.Equal(t, int64(maxGas), int64(totalCost))
 }
 
+func TestHandleStorageLoopHostOutOfGas(t *testing.T) {
+	cache, cleanup := withCache(t)
+	defer cleanup()
+	id := createTestContract(t, cache)
+
+	gasMeter1 := NewMockGasMeter(100000000)
+	igasMeter1 := GasMeter(gasMeter1)
+	// instantiate it with this store
+	store := NewLookup(gasMeter1)
+	api := NewMockAPI()
+	balance := types.Coins{types.NewCoin(250, "ATOM")}
+	querier := DefaultQuerier(mockContractAddr, balance)
+	params, err := json.Marshal(mockEnv("creator"))
+	require.NoError(t, err)
+
+	msg := []byte(`{"verifier": "fred", "beneficiary": "bob"}`)
+	res, _, err := Instantiate(cache, id, params, msg, &igasMeter1, store, api, &querier, 100000000)
+	require.NoError(t, err)
+	requireOkResponse(t, res, 0)
+
+	// the sdk meter has far less gas than the VM, so storage runs out first
+	gasMeter2 := NewMockGasMeter(2000000)
+	igasMeter2 := GasMeter(gasMeter2)
+	store.SetGasMeter(gasMeter2)
+	params, err = json.Marshal(mockEnv("fred"))
+	require.NoError(t, err)
+	_, cost, err := Handle(cache, id, params, []byte(`{"storage_loop":{}}`), &igasMeter2, store, api, &querier, 100000000)
+	require.Error(t, err)
+	assert.Equal(t, types.ErrOutOfGasHost, err)
+	assert.IsType(t, types.OutOfGasHostError{}, err)
+	assert.True(t, errors.Is(err, types.OutOfGasError{}))
+	assert.False(t, errors.Is(err, types.ErrOutOfGasWasm))
+	assert.Less(t, cost, uint64(100000000))
+}
+
 func TestHandleUserErrorsInApiCalls(t *testing.T) {
 	cache, cleanup := withCache(t)
 	defer cleanup()
@@ -485,6 +575,28 @@ func TestQuery(t *testing.T) {
 	require.Equal(t, string(qres.Ok), `{"verifier":"fred"}`)
 }
 
+func TestQueryRejectsWrites(t *testing.T) {
+	gasMeter := NewMockGasMeter(100000000)
+	igasMeter := GasMeter(gasMeter)
+	store := NewLookup(gasMeter)
+	store.Set([]byte("foo"), []byte("bar"))
+
+	// no test contract writes in query, so the callbacks are called like the VM does
+	state := buildQueryDBState(store, 0)
+	err := callDBWrite(&state, &igasMeter, []byte("foo"), []byte("baz"))
+	assert.EqualError(t, err, "db_write not allowed in query")
+	err = callDBRemove(&state, &igasMeter, []byte("foo"))
+	assert.EqualError(t, err, "db_remove not allowed in query")
+	assert.Equal(t, []byte("bar"), store.Get([]byte("foo")))
+
+	// the same calls succeed outside of queries
+	state = buildDBState(store, 0)
+	require.NoError(t, callDBWrite(&state, &igasMeter, []byte("foo"), []byte("baz")))
+	assert.Equal(t, []byte("baz"), store.Get([]byte("foo")))
+	require.NoError(t, callDBRemove(&state, &igasMeter, []byte("foo")))
+	assert.Nil(t, store.Get([]byte("foo")))
+}
+
 func TestHackatomQuerier(t *testing.T) {
 	cache, cleanup := withCache(t)
 	defer cleanup()
@@ -58,6 +58,23 @@ func receiveVector(b C.Buffer) []byte {
 	return res
 }
 
+// Give read access to an owned vector that was passed to us, without copying it.
+// The returned slice points into Rust memory, so it must not be used after calling freeVector on b.
+func viewVector(b C.Buffer) []byte {
+	if bufIsNil(b) {
+		return nil
+	}
+	n := int(b.len)
+	return (*[1 << 30]byte)(unsafe.Pointer(b.ptr))[:n:n]
+}
+
+// Free an owned vector that was passed to us, without reading it.
+func freeVector(b C.Buffer) {
+	if !bufIsNil(b) {
+		C.free_rust(b)
+	}
+}
+
 // Copy the contents of a vector that was allocated on the Rust side.
 // Unlike receiveVector, we do not free it, because it will be manually
 // freed on the Rust side after control returns to it.
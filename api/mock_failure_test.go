@@ -18,3 +18,14 @@ func NewMockFailureAPI() *GoAPI {
 		CanonicalAddress: MockFailureCanonicalAddress,
 	}
 }
+
+func MockInvalidUTF8HumanAddress(canon []byte) (string, uint64, error) {
+	return "\xff\xfe", 0, nil
+}
+
+func NewMockInvalidUTF8API() *GoAPI {
+	return &GoAPI{
+		HumanAddress:     MockInvalidUTF8HumanAddress,
+		CanonicalAddress: MockCanonicalAddress,
+	}
+}
@@ -0,0 +1,62 @@
+package cosmwasm
+
+import (
+	"fmt"
+
+	"github.com/CosmWasm/go-cosmwasm/types"
+)
+
+// ExecuteStep is a single message of ExecuteMultiple
+type ExecuteStep struct {
+	Code CodeID
+	Env  types.Env
+	Msg  []byte
+}
+
+// ExecuteStepResult is the result of a successful step of ExecuteMultiple
+type ExecuteStepResult struct {
+	Response *types.HandleResponse
+	GasUsed  uint64
+}
+
+// StepError is returned by ExecuteMultiple when a step fails
+type StepError struct {
+	// Index is the index of the failed step
+	Index int
+	Err   error
+}
+
+func (e StepError) Error() string {
+	return fmt.Sprintf("step %d: %v", e.Index, e.Err)
+}
+
+func (e StepError) Unwrap() error {
+	return e.Err
+}
+
+// ExecuteMultiple executes the steps in order, possibly on different codes, against the same store.
+// The steps share gasLimit, so each one gets what the previous ones left. Writes are only committed
+// to store if all steps succeed. Otherwise a StepError with the index of the failed step is returned,
+// along with the results of the steps before it.
+func (w *Wasmer) ExecuteMultiple(
+	steps []ExecuteStep,
+	store KVStore,
+	goapi GoAPI,
+	querier Querier,
+	gasMeter GasMeter,
+	gasLimit uint64,
+) ([]ExecuteStepResult, uint64, error) {
+	cache := NewCacheKVStore(store)
+	results := make([]ExecuteStepResult, 0, len(steps))
+	var gasUsed uint64
+	for i, step := range steps {
+		res, gas, err := w.Execute(step.Code, step.Env, step.Msg, cache, goapi, querier, gasMeter, gasLimit-gasUsed)
+		gasUsed += gas
+		if err != nil {
+			return results, gasUsed, StepError{Index: i, Err: err}
+		}
+		results = append(results, ExecuteStepResult{Response: res, GasUsed: gas})
+	}
+	cache.Write()
+	return results, gasUsed, nil
+}
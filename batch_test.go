@@ -0,0 +1,43 @@
+package cosmwasm
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/CosmWasm/go-cosmwasm/types"
+)
+
+func TestExecuteMultiple(t *testing.T) {
+	wasmer, cleanup := withWasmer(t, types.VMConfig{})
+	defer cleanup()
+	queue, _ := createTestCode(t, wasmer, "queue.wasm")
+	hackatom, _ := createTestCode(t, wasmer, "hackatom.wasm")
+
+	store := newMemStore()
+	_, _, err := wasmer.Instantiate(queue, mockEnv("creator"), []byte(`{}`), store, mockAPI(), nil, &readOnlyMeter{}, 100000000)
+	require.NoError(t, err)
+	count := func() string {
+		res, _, err := wasmer.Query(queue, []byte(`{"count":{}}`), store, mockAPI(), nil, &readOnlyMeter{}, 100000000)
+		require.NoError(t, err)
+		return string(res)
+	}
+
+	enqueue := ExecuteStep{Code: queue, Env: mockEnv("creator"), Msg: []byte(`{"enqueue":{"value":17}}`)}
+	results, gasUsed, err := wasmer.ExecuteMultiple([]ExecuteStep{enqueue, enqueue}, store, mockAPI(), nil, &readOnlyMeter{}, 100000000)
+	require.NoError(t, err)
+	require.Len(t, results, 2)
+	assert.Equal(t, results[0].GasUsed+results[1].GasUsed, gasUsed)
+	assert.Equal(t, `{"count":2}`, count())
+
+	// hackatom was never instantiated in this store, so it fails and nothing is written
+	release := ExecuteStep{Code: hackatom, Env: mockEnv("fred"), Msg: []byte(`{"release":{}}`)}
+	results, _, err = wasmer.ExecuteMultiple([]ExecuteStep{enqueue, release, enqueue}, store, mockAPI(), nil, &readOnlyMeter{}, 100000000)
+	var stepErr StepError
+	require.True(t, errors.As(err, &stepErr))
+	assert.Equal(t, 1, stepErr.Index)
+	assert.Len(t, results, 1)
+	assert.Equal(t, `{"count":2}`, count())
+}
@@ -0,0 +1,234 @@
+package cosmwasm
+
+import (
+	"bytes"
+	"sort"
+
+	dbm "github.com/tendermint/tm-db"
+)
+
+// CacheKVStore buffers all writes to a parent KVStore in memory until Write is called.
+// Reads and iterators see the buffered writes merged with the parent. It is not safe for concurrent use.
+type CacheKVStore struct {
+	parent KVStore
+	writes map[string]cacheValue
+}
+
+var _ KVStore = (*CacheKVStore)(nil)
+
+type cacheValue struct {
+	value   []byte
+	deleted bool
+}
+
+// NewCacheKVStore returns an empty cache on top of parent
+func NewCacheKVStore(parent KVStore) *CacheKVStore {
+	return &CacheKVStore{
+		parent: parent,
+		writes: make(map[string]cacheValue),
+	}
+}
+
+// RunWithSnapshot runs fn on a cache of store and writes the changes to store only if fn succeeds.
+// Use it to run an entry point and discard all its writes if the contract fails:
+//
+//	err := RunWithSnapshot(store, func(cache KVStore) error {
+//		_, _, err := wasmer.Execute(code, env, msg, cache, goapi, querier, gasMeter, gasLimit)
+//		return err
+//	})
+func RunWithSnapshot(store KVStore, fn func(cache KVStore) error) error {
+	cache := NewCacheKVStore(store)
+	if err := fn(cache); err != nil {
+		return err
+	}
+	cache.Write()
+	return nil
+}
+
+// Get returns the buffered value of key, or the value in the parent if it was not written
+func (s *CacheKVStore) Get(key []byte) []byte {
+	if v, ok := s.writes[string(key)]; ok {
+		if v.deleted {
+			return nil
+		}
+		return v.value
+	}
+	return s.parent.Get(key)
+}
+
+// Set buffers a write of key
+func (s *CacheKVStore) Set(key, value []byte) {
+	s.writes[string(key)] = cacheValue{value: copyBytes(value)}
+}
+
+// Delete buffers a delete of key
+func (s *CacheKVStore) Delete(key []byte) {
+	s.writes[string(key)] = cacheValue{deleted: true}
+}
+
+// Iterator iterates over the buffered writes merged with the parent in ascending order
+func (s *CacheKVStore) Iterator(start, end []byte) dbm.Iterator {
+	return s.newIterator(start, end, true)
+}
+
+// ReverseIterator iterates over the buffered writes merged with the parent in descending order
+func (s *CacheKVStore) ReverseIterator(start, end []byte) dbm.Iterator {
+	return s.newIterator(start, end, false)
+}
+
+// Write applies all buffered writes to the parent in key order and clears the cache
+func (s *CacheKVStore) Write() {
+	for _, key := range s.sortedKeys(nil, nil) {
+		v := s.writes[key]
+		if v.deleted {
+			s.parent.Delete([]byte(key))
+		} else {
+			s.parent.Set([]byte(key), v.value)
+		}
+	}
+	s.writes = make(map[string]cacheValue)
+}
+
+// EstimateCount returns the estimate of the parent plus the number of buffered writes in [start, end),
+// if the parent implements IteratorCountEstimator. See IteratorCountEstimator.
+func (s *CacheKVStore) EstimateCount(start, end []byte) (uint64, bool) {
+	count, ok := estimateCount(s.parent, start, end)
+	if !ok {
+		return 0, false
+	}
+	return count + uint64(len(s.sortedKeys(start, end))), true
+}
+
+// sortedKeys returns the buffered keys in [start, end) in ascending order
+func (s *CacheKVStore) sortedKeys(start, end []byte) []string {
+	var keys []string
+	for key := range s.writes {
+		k := []byte(key)
+		if (start == nil || bytes.Compare(k, start) >= 0) && (end == nil || bytes.Compare(k, end) < 0) {
+			keys = append(keys, key)
+		}
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+func (s *CacheKVStore) newIterator(start, end []byte, ascending bool) dbm.Iterator {
+	var parent dbm.Iterator
+	if ascending {
+		parent = s.parent.Iterator(start, end)
+	} else {
+		parent = s.parent.ReverseIterator(start, end)
+	}
+	keys := s.sortedKeys(start, end)
+	entries := make([]cacheEntry, len(keys))
+	for i, key := range keys {
+		if !ascending {
+			i = len(keys) - 1 - i
+		}
+		entries[i] = cacheEntry{key: []byte(key), cacheValue: s.writes[key]}
+	}
+	it := &cacheIterator{parent: parent, cache: entries, ascending: ascending, start: start, end: end}
+	it.skip()
+	return it
+}
+
+type cacheEntry struct {
+	key []byte
+	cacheValue
+}
+
+// cacheIterator merges an iterator of the parent store with a snapshot of the buffered writes.
+// Buffered writes shadow parent entries with the same key, and deleted entries are skipped.
+type cacheIterator struct {
+	parent     dbm.Iterator
+	cache      []cacheEntry
+	pos        int
+	ascending  bool
+	start, end []byte
+}
+
+var _ dbm.Iterator = (*cacheIterator)(nil)
+
+// compare orders keys in iteration order
+func (it *cacheIterator) compare(a, b []byte) int {
+	if it.ascending {
+		return bytes.Compare(a, b)
+	}
+	return bytes.Compare(b, a)
+}
+
+// useCache returns true if the current entry comes from the cache
+func (it *cacheIterator) useCache() bool {
+	if it.pos >= len(it.cache) {
+		return false
+	}
+	return !it.parent.Valid() || it.compare(it.parent.Key(), it.cache[it.pos].key) > 0
+}
+
+// skip moves past parent entries shadowed by the cache and deleted cache entries
+func (it *cacheIterator) skip() {
+	for it.pos < len(it.cache) {
+		if it.parent.Valid() {
+			cmp := it.compare(it.parent.Key(), it.cache[it.pos].key)
+			if cmp < 0 {
+				return
+			}
+			if cmp == 0 {
+				it.parent.Next()
+				continue
+			}
+		}
+		if !it.cache[it.pos].deleted {
+			return
+		}
+		it.pos++
+	}
+}
+
+func (it *cacheIterator) Domain() ([]byte, []byte) {
+	return it.start, it.end
+}
+
+func (it *cacheIterator) Valid() bool {
+	return it.pos < len(it.cache) || it.parent.Valid()
+}
+
+func (it *cacheIterator) Next() {
+	if !it.Valid() {
+		panic("iterator is invalid")
+	}
+	if it.useCache() {
+		it.pos++
+	} else {
+		it.parent.Next()
+	}
+	it.skip()
+}
+
+func (it *cacheIterator) Key() []byte {
+	if !it.Valid() {
+		panic("iterator is invalid")
+	}
+	if it.useCache() {
+		return it.cache[it.pos].key
+	}
+	return it.parent.Key()
+}
+
+func (it *cacheIterator) Value() []byte {
+	if !it.Valid() {
+		panic("iterator is invalid")
+	}
+	if it.useCache() {
+		return it.cache[it.pos].value
+	}
+	return it.parent.Value()
+}
+
+func (it *cacheIterator) Error() error {
+	return it.parent.Error()
+}
+
+func (it *cacheIterator) Close() {
+	it.parent.Close()
+}
@@ -0,0 +1,76 @@
+package cosmwasm
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	dbm "github.com/tendermint/tm-db"
+)
+
+func collect(iter dbm.Iterator) []string {
+	defer iter.Close()
+	var res []string
+	for ; iter.Valid(); iter.Next() {
+		res = append(res, string(iter.Key())+"="+string(iter.Value()))
+	}
+	return res
+}
+
+func TestCacheKVStore(t *testing.T) {
+	parent := newMemStore()
+	for _, k := range []string{"a", "c", "e", "g"} {
+		parent.Set([]byte(k), []byte(k))
+	}
+	cache := NewCacheKVStore(parent)
+	cache.Set([]byte("b"), []byte("B"))
+	cache.Set([]byte("c"), []byte("C"))
+	cache.Delete([]byte("e"))
+	cache.Delete([]byte("f"))
+	cache.Set([]byte("h"), []byte("H"))
+
+	assert.Equal(t, []byte("C"), cache.Get([]byte("c")))
+	assert.Nil(t, cache.Get([]byte("e")))
+	assert.Equal(t, []byte("g"), cache.Get([]byte("g")))
+	// parent is untouched
+	assert.Equal(t, []byte("e"), parent.Get([]byte("e")))
+
+	assert.Equal(t, []string{"a=a", "b=B", "c=C", "g=g", "h=H"}, collect(cache.Iterator(nil, nil)))
+	assert.Equal(t, []string{"h=H", "g=g", "c=C", "b=B", "a=a"}, collect(cache.ReverseIterator(nil, nil)))
+	assert.Equal(t, []string{"b=B", "c=C"}, collect(cache.Iterator([]byte("b"), []byte("g"))))
+	assert.Equal(t, []string{"g=g", "c=C"}, collect(cache.ReverseIterator([]byte("c"), []byte("h"))))
+
+	cache.Write()
+	assert.Equal(t, []string{"a=a", "b=B", "c=C", "g=g", "h=H"}, collect(parent.Iterator(nil, nil)))
+	assert.Equal(t, []string{"a=a", "b=B", "c=C", "g=g", "h=H"}, collect(cache.Iterator(nil, nil)))
+}
+
+func TestCacheKVStoreDeletedTail(t *testing.T) {
+	parent := newMemStore()
+	parent.Set([]byte("a"), []byte("a"))
+	cache := NewCacheKVStore(parent)
+	cache.Delete([]byte("a"))
+	cache.Delete([]byte("b"))
+	assert.Empty(t, collect(cache.Iterator(nil, nil)))
+	assert.Empty(t, collect(cache.ReverseIterator(nil, nil)))
+}
+
+func TestRunWithSnapshot(t *testing.T) {
+	store := newMemStore()
+	err := RunWithSnapshot(store, func(cache KVStore) error {
+		cache.Set([]byte("foo"), []byte("bar"))
+		return nil
+	})
+	require.NoError(t, err)
+	assert.Equal(t, []byte("bar"), store.Get([]byte("foo")))
+
+	failure := errors.New("contract failed")
+	err = RunWithSnapshot(store, func(cache KVStore) error {
+		cache.Set([]byte("foo"), []byte("baz"))
+		cache.Delete([]byte("foo"))
+		return failure
+	})
+	assert.Equal(t, failure, err)
+	assert.Equal(t, []byte("bar"), store.Get([]byte("foo")))
+}
@@ -0,0 +1,148 @@
+package cosmwasm
+
+import (
+	"errors"
+	"runtime/debug"
+
+	dbm "github.com/tendermint/tm-db"
+
+	"github.com/CosmWasm/go-cosmwasm/types"
+)
+
+// callContext holds the store, api and querier of a single contract call,
+// wrapped with the host side features of the Wasmer
+type callContext struct {
+	store   KVStore
+	api     GoAPI
+	querier Querier
+	panics  *panicGuard
+}
+
+func (w *Wasmer) newCallContext(store KVStore, goapi GoAPI, querier Querier, gasMeter GasMeter) *callContext {
+	if len(w.config.AllowedKeyPrefixes) > 0 {
+		store = prefixGuardStore{KVStore: store, prefixes: w.config.AllowedKeyPrefixes}
+	}
+	if w.config.GasConfig != (types.GasConfig{}) {
+		store = iteratorGasStore{KVStore: store, config: w.config.GasConfig, gasMeter: gasMeter}
+	}
+	profiler, _ := gasMeter.(HostGasProfiler)
+	if profiler != nil || w.logger != nil {
+		ins := hostInstrument{meter: gasMeter, profiler: profiler, logger: w.logger}
+		store = instrumentedStore{KVStore: store, hostInstrument: ins}
+		goapi = ins.instrumentAPI(goapi)
+		querier = instrumentedQuerier{Querier: querier, hostInstrument: ins}
+	}
+	panics := &panicGuard{}
+	return &callContext{
+		store:   guardedStore{KVStore: store, panics: panics},
+		api:     panics.guardAPI(goapi),
+		querier: guardedQuerier{Querier: w.wrapQuerier(querier), panics: panics},
+		panics:  panics,
+	}
+}
+
+// panicGuard records the first panic raised by a host callback during a call, so it can be
+// reported as a HostPanicError once the call returns. The panic itself is raised again, as the
+// cgo callbacks turn it into an error for the VM (and detect out of gas panics).
+type panicGuard struct {
+	value interface{}
+	stack []byte
+}
+
+// record must be deferred by every guarded callback
+func (g *panicGuard) record() {
+	if r := recover(); r != nil {
+		if g.value == nil {
+			g.value = r
+			g.stack = debug.Stack()
+		}
+		panic(r)
+	}
+}
+
+// check replaces the error of a failed call with a HostPanicError if a callback panicked.
+// Running out of gas also panics in the sdk, but is reported as such.
+func (g *panicGuard) check(err error) error {
+	if err == nil || g.value == nil || errors.Is(err, types.OutOfGasError{}) {
+		return err
+	}
+	return types.HostPanicError{Value: g.value, Stack: g.stack}
+}
+
+func (g *panicGuard) guardAPI(goapi GoAPI) GoAPI {
+	return GoAPI{
+		HumanAddress: func(canon []byte) (string, uint64, error) {
+			defer g.record()
+			return goapi.HumanAddress(canon)
+		},
+		CanonicalAddress: func(human string) ([]byte, uint64, error) {
+			defer g.record()
+			return goapi.CanonicalAddress(human)
+		},
+	}
+}
+
+type guardedStore struct {
+	KVStore
+	panics *panicGuard
+}
+
+func (s guardedStore) Get(key []byte) []byte {
+	defer s.panics.record()
+	return s.KVStore.Get(key)
+}
+
+func (s guardedStore) Set(key, value []byte) {
+	defer s.panics.record()
+	s.KVStore.Set(key, value)
+}
+
+func (s guardedStore) Delete(key []byte) {
+	defer s.panics.record()
+	s.KVStore.Delete(key)
+}
+
+func (s guardedStore) Iterator(start, end []byte) dbm.Iterator {
+	defer s.panics.record()
+	return guardedIterator{Iterator: s.KVStore.Iterator(start, end), panics: s.panics}
+}
+
+func (s guardedStore) ReverseIterator(start, end []byte) dbm.Iterator {
+	defer s.panics.record()
+	return guardedIterator{Iterator: s.KVStore.ReverseIterator(start, end), panics: s.panics}
+}
+
+type guardedIterator struct {
+	dbm.Iterator
+	panics *panicGuard
+}
+
+func (it guardedIterator) Valid() bool {
+	defer it.panics.record()
+	return it.Iterator.Valid()
+}
+
+func (it guardedIterator) Next() {
+	defer it.panics.record()
+	it.Iterator.Next()
+}
+
+func (it guardedIterator) Key() []byte {
+	defer it.panics.record()
+	return it.Iterator.Key()
+}
+
+func (it guardedIterator) Value() []byte {
+	defer it.panics.record()
+	return it.Iterator.Value()
+}
+
+type guardedQuerier struct {
+	Querier
+	panics *panicGuard
+}
+
+func (q guardedQuerier) Query(request types.QueryRequest, gasLimit uint64) ([]byte, error) {
+	defer q.panics.record()
+	return q.Querier.Query(request, gasLimit)
+}
@@ -0,0 +1,56 @@
+package cosmwasm
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/CosmWasm/go-cosmwasm/types"
+)
+
+type panickingStore struct {
+	memStore
+}
+
+func (s panickingStore) Get(key []byte) []byte {
+	panic("database corrupted")
+}
+
+func TestPanicGuard(t *testing.T) {
+	g := &panicGuard{}
+	store := guardedStore{KVStore: panickingStore{newMemStore()}, panics: g}
+
+	// the panic is recorded and raised again for the callbacks
+	assert.PanicsWithValue(t, "database corrupted", func() { store.Get([]byte("foo")) })
+	assert.Equal(t, "database corrupted", g.value)
+	assert.NotEmpty(t, g.stack)
+
+	// only failed calls are affected
+	assert.NoError(t, g.check(nil))
+	err := g.check(errors.New("Panic in Go callback"))
+	var hostErr types.HostPanicError
+	require.True(t, errors.As(err, &hostErr))
+	assert.Equal(t, "database corrupted", hostErr.Value)
+	assert.Equal(t, "panic in host callback: database corrupted", err.Error())
+
+	// running out of gas is reported as such
+	assert.Equal(t, types.ErrOutOfGasHost, g.check(types.ErrOutOfGasHost))
+
+	// no panic, no change
+	plain := errors.New("foo")
+	assert.Equal(t, plain, (&panicGuard{}).check(plain))
+}
+
+func TestQueryReportsHostPanic(t *testing.T) {
+	wasmer, cleanup := withWasmer(t, types.VMConfig{})
+	defer cleanup()
+	id, _ := createTestCode(t, wasmer, "hackatom.wasm")
+
+	store := panickingStore{newMemStore()}
+	_, _, err := wasmer.Query(id, []byte(`{"verifier":{}}`), store, GoAPI{}, nil, &readOnlyMeter{}, 100000000)
+	var hostErr types.HostPanicError
+	require.True(t, errors.As(err, &hostErr), "unexpected error: %v", err)
+	assert.Equal(t, "database corrupted", hostErr.Value)
+}
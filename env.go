@@ -0,0 +1,34 @@
+package cosmwasm
+
+import (
+	"bytes"
+	"encoding/json"
+
+	"github.com/CosmWasm/go-cosmwasm/types"
+)
+
+// serializeEnv encodes the env that is passed to the contract.
+// With CanonicalEnv set, the json is canonicalized (see canonicalJSON).
+func (w *Wasmer) serializeEnv(env types.Env) ([]byte, error) {
+	bz, err := json.Marshal(env)
+	if err != nil {
+		return nil, err
+	}
+	if w.config.CanonicalEnv {
+		return canonicalJSON(bz)
+	}
+	return bz, nil
+}
+
+// canonicalJSON re-encodes the json document with all object keys sorted and no insignificant whitespace.
+// Numbers are kept as they are, so large integers do not lose precision.
+func canonicalJSON(bz []byte) ([]byte, error) {
+	dec := json.NewDecoder(bytes.NewReader(bz))
+	dec.UseNumber()
+	var doc interface{}
+	if err := dec.Decode(&doc); err != nil {
+		return nil, err
+	}
+	// maps are always encoded with sorted keys
+	return json.Marshal(doc)
+}
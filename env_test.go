@@ -0,0 +1,41 @@
+package cosmwasm
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/CosmWasm/go-cosmwasm/types"
+)
+
+func TestCanonicalJSON(t *testing.T) {
+	bz, err := canonicalJSON([]byte(`{"b": {"z": 1, "a": [3, 2]}, "a": 18446744073709551615}`))
+	require.NoError(t, err)
+	assert.Equal(t, `{"a":18446744073709551615,"b":{"a":[3,2],"z":1}}`, string(bz))
+
+	_, err = canonicalJSON([]byte(`{"a":`))
+	assert.Error(t, err)
+}
+
+func TestSerializeEnv(t *testing.T) {
+	env := types.Env{
+		Block: types.BlockInfo{Height: 123, Time: 1578939743, ChainID: "foobar"},
+		Message: types.MessageInfo{
+			Sender:    "creator",
+			SentFunds: types.Coins{types.NewCoin(100, "ATOM")},
+		},
+		Contract: types.ContractInfo{Address: "contract"},
+	}
+
+	// by default we keep the field order of the go struct
+	w := &Wasmer{}
+	bz, err := w.serializeEnv(env)
+	require.NoError(t, err)
+	assert.Equal(t, `{"block":{"height":123,"time":1578939743,"chain_id":"foobar"},"message":{"sender":"creator","sent_funds":[{"denom":"ATOM","amount":"100"}]},"contract":{"address":"contract"}}`, string(bz))
+
+	w = &Wasmer{config: types.VMConfig{CanonicalEnv: true}}
+	bz, err = w.serializeEnv(env)
+	require.NoError(t, err)
+	assert.Equal(t, `{"block":{"chain_id":"foobar","height":123,"time":1578939743},"contract":{"address":"contract"},"message":{"sender":"creator","sent_funds":[{"amount":"100","denom":"ATOM"}]}}`, string(bz))
+}
@@ -0,0 +1,179 @@
+package cosmwasm
+
+import (
+	dbm "github.com/tendermint/tm-db"
+
+	"github.com/CosmWasm/go-cosmwasm/types"
+)
+
+// SDKGasMeter is the subset of the cosmos-sdk GasMeter we need in order to charge gas on it
+// https://github.com/cosmos/cosmos-sdk/blob/18890a225b46260a9adc587be6fa1cc2aff101cd/store/types/gas.go#L34
+type SDKGasMeter interface {
+	GasConsumed() uint64
+	ConsumeGas(amount uint64, descriptor string)
+}
+
+// WasmGasMeter is a GasMeter that can be charged with the gas used inside the VM.
+// If the GasMeter passed to an entry point implements it, the gas used by the contract itself
+// is consumed on it once the call completes. Gas used by host callbacks (storage, queries)
+// is already charged on the meter by the callbacks themselves, so it is not charged twice.
+type WasmGasMeter interface {
+	GasMeter
+	ConsumeWasmGas(amount uint64, descriptor string)
+}
+
+// MultipliedGasMeter adapts an sdk gas meter to the wasm gas units used by the VM.
+// One sdk gas unit corresponds to `multiplier` wasm gas units.
+type MultipliedGasMeter struct {
+	meter      SDKGasMeter
+	multiplier uint64
+	// remainder is the wasm gas charged so far that does not add up to a full sdk gas unit
+	remainder *uint64
+}
+
+var _ WasmGasMeter = MultipliedGasMeter{}
+
+// NewMultipliedGasMeter wraps the given sdk gas meter. Multiplier must be non-zero.
+func NewMultipliedGasMeter(meter SDKGasMeter, multiplier uint64) MultipliedGasMeter {
+	if multiplier == 0 {
+		panic("gas multiplier must be non-zero")
+	}
+	return MultipliedGasMeter{
+		meter:      meter,
+		multiplier: multiplier,
+		remainder:  new(uint64),
+	}
+}
+
+// GasConsumed returns the gas consumed on the sdk meter in wasm gas units
+func (m MultipliedGasMeter) GasConsumed() uint64 {
+	return m.meter.GasConsumed() * m.multiplier
+}
+
+// ConsumeWasmGas charges the given amount of wasm gas to the sdk meter. Amounts that do not add up
+// to a full sdk gas unit are carried over to the next charge, so small charges like the per key
+// iteration costs are not lost to rounding.
+func (m MultipliedGasMeter) ConsumeWasmGas(amount uint64, descriptor string) {
+	total := *m.remainder + amount
+	*m.remainder = total % m.multiplier
+	m.meter.ConsumeGas(total/m.multiplier, descriptor)
+}
+
+// chargeWasmGas consumes the gas used inside the VM on gasMeter, if it is a WasmGasMeter.
+// gasBefore is the value of gasMeter.GasConsumed() before the call was made.
+func chargeWasmGas(gasMeter GasMeter, gasBefore uint64, gasUsed uint64) {
+	wm, ok := gasMeter.(WasmGasMeter)
+	if !ok {
+		return
+	}
+	// this part was charged by the host callbacks during the call
+	external := gasMeter.GasConsumed() - gasBefore
+	if gasUsed > external {
+		wm.ConsumeWasmGas(gasUsed-external, "wasm contract")
+	}
+}
+
+// canConsumeGas returns true if consumeGas can charge gas on gasMeter
+func canConsumeGas(gasMeter GasMeter) bool {
+	switch gasMeter.(type) {
+	case WasmGasMeter, SDKGasMeter:
+		return true
+	}
+	return false
+}
+
+// consumeGas charges gas on gasMeter in its own units. It panics if the meter cannot be charged,
+// use canConsumeGas to check first.
+func consumeGas(gasMeter GasMeter, amount uint64, descriptor string) {
+	switch m := gasMeter.(type) {
+	case WasmGasMeter:
+		m.ConsumeWasmGas(amount, descriptor)
+	case SDKGasMeter:
+		m.ConsumeGas(amount, descriptor)
+	default:
+		panic("gas meter can not be charged")
+	}
+}
+
+// checkGasMeter ensures the gas meter of a call can be charged with the costs of the GasConfig
+func (w *Wasmer) checkGasMeter(gasMeter GasMeter) error {
+	if w.config.GasConfig != (types.GasConfig{}) && !canConsumeGas(gasMeter) {
+		return types.ErrGasMeterNotChargeable
+	}
+	return nil
+}
+
+// IteratorCountEstimator can be implemented by a KVStore that can estimate the number of keys in a range.
+// With GasConfig.IterCostPerEstimatedKey set, opening an iterator on such a store pre-charges gas for
+// the estimated keys, instead of charging every key and value read through the iterator.
+// ReadOnlyKVStore, RecordingKVStore and CacheKVStore forward it to the store they wrap.
+type IteratorCountEstimator interface {
+	// EstimateCount returns the estimated number of keys in [start, end), or false if it cannot be estimated
+	EstimateCount(start, end []byte) (uint64, bool)
+}
+
+// estimateCount returns the estimate of store for [start, end), if it implements IteratorCountEstimator.
+// The stores wrapping another one in this package use it to forward EstimateCount.
+func estimateCount(store KVStore, start, end []byte) (uint64, bool) {
+	estimator, ok := store.(IteratorCountEstimator)
+	if !ok {
+		return 0, false
+	}
+	return estimator.EstimateCount(start, end)
+}
+
+// iteratorGasStore charges the iteration costs of the GasConfig on the gas meter of a call.
+// The callbacks measure the gas consumed on the meter, so this is charged to the contract like any storage gas.
+type iteratorGasStore struct {
+	KVStore
+	config   types.GasConfig
+	gasMeter GasMeter
+}
+
+func (s iteratorGasStore) Iterator(start, end []byte) dbm.Iterator {
+	prepaid := s.chargeIterator(start, end)
+	return iteratorGas{Iterator: s.KVStore.Iterator(start, end), store: s, prepaid: prepaid}
+}
+
+func (s iteratorGasStore) ReverseIterator(start, end []byte) dbm.Iterator {
+	prepaid := s.chargeIterator(start, end)
+	return iteratorGas{Iterator: s.KVStore.ReverseIterator(start, end), store: s, prepaid: prepaid}
+}
+
+// chargeIterator charges for opening an iterator over [start, end).
+// It returns true if the keys of the range were pre-charged based on an estimate.
+func (s iteratorGasStore) chargeIterator(start, end []byte) bool {
+	consumeGas(s.gasMeter, s.config.IterCreateCost, "iterator create")
+	if s.config.IterCostPerEstimatedKey == 0 {
+		return false
+	}
+	count, ok := estimateCount(s.KVStore, start, end)
+	if !ok {
+		return false
+	}
+	consumeGas(s.gasMeter, count*s.config.IterCostPerEstimatedKey, "iterator estimated keys")
+	return true
+}
+
+type iteratorGas struct {
+	dbm.Iterator
+	store iteratorGasStore
+	// prepaid is true if the keys were charged when the iterator was opened
+	prepaid bool
+}
+
+func (it iteratorGas) Key() []byte {
+	key := it.Iterator.Key()
+	if !it.prepaid {
+		consumeGas(it.store.gasMeter, uint64(len(key))*it.store.config.IterNextCostPerByte, "iterator next")
+	}
+	return key
+}
+
+func (it iteratorGas) Value() []byte {
+	value := it.Iterator.Value()
+	if !it.prepaid {
+		consumeGas(it.store.gasMeter, uint64(len(value))*it.store.config.IterNextCostPerByte, "iterator next")
+	}
+	return value
+}
@@ -0,0 +1,192 @@
+package cosmwasm
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/CosmWasm/go-cosmwasm/types"
+)
+
+type sdkMeter struct {
+	consumed uint64
+}
+
+func (m *sdkMeter) GasConsumed() uint64 {
+	return m.consumed
+}
+
+func (m *sdkMeter) ConsumeGas(amount uint64, descriptor string) {
+	m.consumed += amount
+}
+
+func TestMultipliedGasMeter(t *testing.T) {
+	sdk := &sdkMeter{consumed: 5}
+	meter := NewMultipliedGasMeter(sdk, 100)
+	assert.Equal(t, uint64(500), meter.GasConsumed())
+
+	meter.ConsumeWasmGas(1234, "test")
+	assert.Equal(t, uint64(17), sdk.consumed)
+	assert.Equal(t, uint64(1700), meter.GasConsumed())
+
+	// the remaining 34 are carried over
+	meter.ConsumeWasmGas(66, "test")
+	assert.Equal(t, uint64(18), sdk.consumed)
+	meter.ConsumeWasmGas(99, "test")
+	assert.Equal(t, uint64(18), sdk.consumed)
+}
+
+func TestChargeWasmGas(t *testing.T) {
+	sdk := &sdkMeter{consumed: 10}
+	meter := NewMultipliedGasMeter(sdk, 100)
+	before := meter.GasConsumed()
+
+	// a host callback charged 3 sdk gas during the call
+	sdk.ConsumeGas(3, "callback")
+	// the contract reports 5000 wasm gas in total, which includes the 300 used by the callback
+	chargeWasmGas(meter, before, 5000)
+	assert.Equal(t, uint64(10+3+47), sdk.consumed)
+
+	// meters that are not WasmGasMeters are left untouched
+	plain := &readOnlyMeter{consumed: 7}
+	chargeWasmGas(plain, 7, 5000)
+	assert.Equal(t, uint64(7), plain.consumed)
+}
+
+type readOnlyMeter struct {
+	consumed uint64
+}
+
+func (m *readOnlyMeter) GasConsumed() uint64 {
+	return m.consumed
+}
+
+func TestIteratorGasStore(t *testing.T) {
+	sdk := &sdkMeter{}
+	mem := newMemStore()
+	mem.Set([]byte("a"), []byte("foo"))
+	mem.Set([]byte("bb"), []byte("barbaz"))
+	config := types.GasConfig{IterCreateCost: 1000, IterNextCostPerByte: 3}
+	w := &Wasmer{config: types.VMConfig{GasConfig: config}}
+	call := w.newCallContext(mem, GoAPI{}, nil, sdk)
+
+	iter := call.store.Iterator(nil, nil)
+	assert.Equal(t, uint64(1000), sdk.consumed)
+	for ; iter.Valid(); iter.Next() {
+		iter.Key()
+		iter.Value()
+	}
+	iter.Close()
+	assert.Equal(t, uint64(1000+(1+3+2+6)*3), sdk.consumed)
+
+	// reads are not charged
+	call.store.Get([]byte("a"))
+	assert.Equal(t, uint64(1036), sdk.consumed)
+
+	// no costs, no wrapping
+	w = &Wasmer{}
+	call = w.newCallContext(mem, GoAPI{}, nil, sdk)
+	_, ok := call.store.(guardedStore).KVStore.(memStore)
+	assert.True(t, ok)
+}
+
+func TestIteratorGasStoreMultiplied(t *testing.T) {
+	sdk := &sdkMeter{}
+	mem := newMemStore()
+	mem.Set([]byte("a"), []byte("foo"))
+	mem.Set([]byte("bb"), []byte("barbaz"))
+	w := &Wasmer{config: types.VMConfig{GasConfig: types.GasConfig{IterNextCostPerByte: 3}}}
+	call := w.newCallContext(mem, GoAPI{}, nil, NewMultipliedGasMeter(sdk, 10))
+
+	iter := call.store.Iterator(nil, nil)
+	for ; iter.Valid(); iter.Next() {
+		iter.Key()
+		iter.Value()
+	}
+	iter.Close()
+	// every single charge is below one sdk gas unit, but they add up
+	assert.Equal(t, uint64((1+3+2+6)*3/10), sdk.consumed)
+}
+
+func TestCheckGasMeter(t *testing.T) {
+	w := &Wasmer{config: types.VMConfig{GasConfig: types.GasConfig{IterCreateCost: 1}}}
+	assert.NoError(t, w.checkGasMeter(&sdkMeter{}))
+	assert.NoError(t, w.checkGasMeter(NewMultipliedGasMeter(&sdkMeter{}, 100)))
+	assert.Equal(t, types.ErrGasMeterNotChargeable, w.checkGasMeter(&readOnlyMeter{}))
+
+	// without costs any meter will do
+	w = &Wasmer{}
+	assert.NoError(t, w.checkGasMeter(&readOnlyMeter{}))
+}
+
+// estimatingStore estimates every range to hold count keys
+type estimatingStore struct {
+	memStore
+	count uint64
+}
+
+func (s estimatingStore) EstimateCount(start, end []byte) (uint64, bool) {
+	return s.count, true
+}
+
+func TestIteratorGasStoreEstimate(t *testing.T) {
+	sdk := &sdkMeter{}
+	mem := newMemStore()
+	mem.Set([]byte("a"), []byte("foo"))
+	config := types.GasConfig{IterCreateCost: 1000, IterNextCostPerByte: 3, IterCostPerEstimatedKey: 50}
+	w := &Wasmer{config: types.VMConfig{GasConfig: config}}
+	call := w.newCallContext(estimatingStore{memStore: mem, count: 10}, GoAPI{}, nil, sdk)
+
+	iter := call.store.ReverseIterator(nil, nil)
+	assert.Equal(t, uint64(1000+10*50), sdk.consumed)
+	for ; iter.Valid(); iter.Next() {
+		iter.Key()
+		iter.Value()
+	}
+	iter.Close()
+	// nothing charged per byte
+	assert.Equal(t, uint64(1500), sdk.consumed)
+
+	// the estimate is used with allowed key prefixes as well
+	sdk.consumed = 0
+	w.config.AllowedKeyPrefixes = [][]byte{[]byte("a")}
+	call = w.newCallContext(estimatingStore{memStore: mem, count: 10}, GoAPI{}, nil, sdk)
+	call.store.Iterator([]byte("a"), []byte("b")).Close()
+	assert.Equal(t, uint64(1500), sdk.consumed)
+	w.config.AllowedKeyPrefixes = nil
+
+	// stores without an estimate are charged per byte
+	sdk.consumed = 0
+	call = w.newCallContext(mem, GoAPI{}, nil, sdk)
+	iter = call.store.Iterator(nil, nil)
+	iter.Key()
+	iter.Value()
+	iter.Close()
+	assert.Equal(t, uint64(1000+4*3), sdk.consumed)
+}
+
+func TestIteratorGasEstimateThroughWrappers(t *testing.T) {
+	config := types.GasConfig{IterCostPerEstimatedKey: 1000}
+	wasmer, cleanup := withWasmer(t, types.VMConfig{GasConfig: config})
+	defer cleanup()
+	id, _ := createTestCode(t, wasmer, "queue.wasm")
+
+	store := estimatingStore{memStore: newMemStore(), count: 7}
+	_, _, err := wasmer.Instantiate(id, mockEnv("creator"), []byte(`{}`), store, mockAPI(), nil, &sdkMeter{}, 100000000)
+	require.NoError(t, err)
+	_, _, err = wasmer.Execute(id, mockEnv("creator"), []byte(`{"enqueue":{"value":17}}`), store, mockAPI(), nil, &sdkMeter{}, 100000000)
+	require.NoError(t, err)
+
+	// queries run on a ReadOnlyKVStore
+	meter := NewProfilingGasMeter(NewMultipliedGasMeter(&sdkMeter{}, 1))
+	_, _, err = wasmer.Query(id, []byte(`{"sum":{}}`), store, mockAPI(), nil, meter, 100000000)
+	require.NoError(t, err)
+	assert.Equal(t, uint64(7*1000), meter.Profile["db_scan"])
+
+	// simulations run on a CacheKVStore
+	meter = NewProfilingGasMeter(NewMultipliedGasMeter(&sdkMeter{}, 1))
+	_, err = wasmer.SimulateExecute(id, mockEnv("creator"), []byte(`{"dequeue":{}}`), store, mockAPI(), nil, meter, 100000000)
+	require.NoError(t, err)
+	assert.Equal(t, uint64(7*1000), meter.Profile["db_scan"])
+}
@@ -0,0 +1,130 @@
+package cosmwasm
+
+import (
+	"errors"
+	"fmt"
+
+	dbm "github.com/tendermint/tm-db"
+
+	"github.com/CosmWasm/go-cosmwasm/api"
+	"github.com/CosmWasm/go-cosmwasm/types"
+)
+
+// healthCheckWasm is a minimal contract with a query that always returns `{}`. It has no imports,
+// allocate always hands out the region at 1024 and the result region at 16 is static data.
+// init and handle are only there to pass validation and trap when called.
+var healthCheckWasm = []byte{
+	0x00, 0x61, 0x73, 0x6d, 0x01, 0x00, 0x00, 0x00,
+	// types: (i32) -> i32, (i32) -> (), () -> (), (i32, i32) -> i32
+	sectionType, 19, 4, 0x60, 1, 0x7f, 1, 0x7f, 0x60, 1, 0x7f, 0, 0x60, 0, 0, 0x60, 2, 0x7f, 0x7f, 1, 0x7f,
+	// functions: allocate, deallocate, query, cosmwasm_vm_version_3, init/handle
+	sectionFunction, 6, 5, 0, 1, 0, 2, 3,
+	// one memory of one page
+	sectionMemory, 3, 1, 0, 1,
+	// exports
+	sectionExport, 82, 7,
+	6, 'm', 'e', 'm', 'o', 'r', 'y', byte(externMemory), 0,
+	8, 'a', 'l', 'l', 'o', 'c', 'a', 't', 'e', byte(externFunc), 0,
+	10, 'd', 'e', 'a', 'l', 'l', 'o', 'c', 'a', 't', 'e', byte(externFunc), 1,
+	5, 'q', 'u', 'e', 'r', 'y', byte(externFunc), 2,
+	21, 'c', 'o', 's', 'm', 'w', 'a', 's', 'm', '_', 'v', 'm', '_', 'v', 'e', 'r', 's', 'i', 'o', 'n', '_', '3', byte(externFunc), 3,
+	4, 'i', 'n', 'i', 't', byte(externFunc), 4,
+	6, 'h', 'a', 'n', 'd', 'l', 'e', byte(externFunc), 4,
+	// code: allocate returns 1024, query returns 16, init/handle are unreachable, the others do nothing
+	sectionCode, 22, 5,
+	5, 0, 0x41, 0x80, 0x08, 0x0b,
+	2, 0, 0x0b,
+	4, 0, 0x41, 0x10, 0x0b,
+	2, 0, 0x0b,
+	3, 0, 0x00, 0x0b,
+	// data
+	sectionData, 53, 2,
+	// at 16: region {offset: 32, capacity: 13, length: 13}, followed by the result at 32
+	0, 0x41, 0x10, 0x0b, 29,
+	32, 0, 0, 0, 13, 0, 0, 0, 13, 0, 0, 0, 0, 0, 0, 0,
+	'{', '"', 'O', 'k', '"', ':', '"', 'e', '3', '0', '=', '"', '}',
+	// at 1024: region {offset: 2048, capacity: 1024, length: 0} for the input
+	0, 0x41, 0x80, 0x08, 0x0b, 12,
+	0x00, 0x08, 0, 0, 0x00, 0x04, 0, 0, 0, 0, 0, 0,
+}
+
+// healthCheckGasLimit is plenty for the health check contract
+const healthCheckGasLimit = 10_000_000
+
+// zeroGasMeter is a GasMeter for calls that never charge gas on the host
+type zeroGasMeter struct{}
+
+func (zeroGasMeter) GasConsumed() uint64 {
+	return 0
+}
+
+// healthCheckResult is the serialized response of the query of the health check contract
+const healthCheckResult = `{"Ok":"e30="}`
+
+// HealthCheck verifies the VM works on this platform by compiling and querying a tiny built-in
+// contract. Use it as a readiness check before accepting contract transactions.
+// Like any code, the contract is stored permanently in the data dir, so it must be enabled with
+// VMConfig.EnableHealthCheck; otherwise it fails with types.ErrHealthCheckDisabled. Later checks reuse the code.
+// The VM is called directly, so the check does not show up in the execution and compile metrics.
+func (w *Wasmer) HealthCheck() error {
+	if !w.config.EnableHealthCheck {
+		return types.ErrHealthCheckDisabled
+	}
+	code, err := api.Create(w.cache, healthCheckWasm)
+	if err != nil {
+		return fmt.Errorf("health check: %w", err)
+	}
+	// the contract has no imports, but if it ever calls back, it gets an empty store and no chain
+	var gasMeter GasMeter = zeroGasMeter{}
+	call := w.newCallContext(ReadOnlyKVStore{emptyStore{}}, GoAPI{}, noQuerier{}, gasMeter)
+	res, _, err := api.Query(w.cache, code, []byte(`{}`), &gasMeter, call.store, &call.api, &call.querier, healthCheckGasLimit)
+	if err != nil {
+		return fmt.Errorf("health check: %w", call.panics.check(err))
+	}
+	if string(res) != healthCheckResult {
+		return fmt.Errorf("health check: unexpected result %q", res)
+	}
+	return nil
+}
+
+// emptyStore is a KVStore without any keys
+type emptyStore struct{}
+
+func (emptyStore) Get(key []byte) []byte {
+	return nil
+}
+
+func (emptyStore) Set(key, value []byte) {
+	panic("write to empty store")
+}
+
+func (emptyStore) Delete(key []byte) {
+	panic("delete in empty store")
+}
+
+func (emptyStore) Iterator(start, end []byte) dbm.Iterator {
+	iter, err := dbm.NewMemDB().Iterator(start, end)
+	if err != nil {
+		panic(err)
+	}
+	return iter
+}
+
+func (emptyStore) ReverseIterator(start, end []byte) dbm.Iterator {
+	iter, err := dbm.NewMemDB().ReverseIterator(start, end)
+	if err != nil {
+		panic(err)
+	}
+	return iter
+}
+
+// noQuerier fails all queries
+type noQuerier struct{}
+
+func (noQuerier) Query(request types.QueryRequest, gasLimit uint64) ([]byte, error) {
+	return nil, errors.New("no querier available")
+}
+
+func (noQuerier) GasConsumed() uint64 {
+	return 0
+}
@@ -0,0 +1,49 @@
+package cosmwasm
+
+import (
+	"bytes"
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/CosmWasm/go-cosmwasm/types"
+)
+
+func TestHealthCheckWasm(t *testing.T) {
+	// the built-in contract is what we expect
+	module, err := parseWasm(healthCheckWasm)
+	require.NoError(t, err)
+	assert.True(t, module.hasExport("memory", externMemory))
+	assert.True(t, module.hasExport("query", externFunc))
+	assert.Empty(t, module.imports)
+	assert.True(t, bytes.Contains(healthCheckWasm, []byte(healthCheckResult)))
+}
+
+func TestHealthCheck(t *testing.T) {
+	// off by default, and nothing is stored then
+	wasmer, cleanup := withWasmer(t, types.VMConfig{})
+	defer cleanup()
+	assert.Equal(t, types.ErrHealthCheckDisabled, wasmer.HealthCheck())
+	_, err := wasmer.GetCode(Checksum(healthCheckWasm))
+	assert.True(t, errors.Is(err, types.ErrCodeNotFound))
+
+	wasmer, cleanup = withWasmer(t, types.VMConfig{EnableHealthCheck: true})
+	defer cleanup()
+	require.NoError(t, wasmer.HealthCheck())
+	// it can run again with the stored code
+	require.NoError(t, wasmer.HealthCheck())
+
+	// the checks are not counted
+	assert.Empty(t, wasmer.GetExecutionCounts())
+	assert.Equal(t, CompileMetrics{}, wasmer.GetCompileMetrics())
+}
+
+func TestEmptyStore(t *testing.T) {
+	store := emptyStore{}
+	assert.Nil(t, store.Get([]byte("foo")))
+	assert.False(t, store.Iterator(nil, nil).Valid())
+	assert.False(t, store.ReverseIterator([]byte("a"), []byte("b")).Valid())
+	assert.Panics(t, func() { store.Set([]byte("foo"), []byte("bar")) })
+}
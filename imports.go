@@ -0,0 +1,29 @@
+package cosmwasm
+
+import (
+	"github.com/CosmWasm/go-cosmwasm/types"
+)
+
+// importModule is the module name contracts import host functions from
+const importModule = "env"
+
+// supportedImports are the host functions the VM provides to cosmwasm_vm_version_3 contracts.
+// db_scan and db_next are only available to contracts built with the iterator feature.
+var supportedImports = []types.FunctionSignature{
+	{Name: "db_read", Params: []string{"i32"}, Results: []string{"i32"}},
+	{Name: "db_write", Params: []string{"i32", "i32"}, Results: []string{}},
+	{Name: "db_remove", Params: []string{"i32"}, Results: []string{}},
+	{Name: "db_scan", Params: []string{"i32", "i32", "i32"}, Results: []string{"i32"}},
+	{Name: "db_next", Params: []string{"i32"}, Results: []string{"i32"}},
+	{Name: "canonicalize_address", Params: []string{"i32", "i32"}, Results: []string{"i32"}},
+	{Name: "humanize_address", Params: []string{"i32", "i32"}, Results: []string{"i32"}},
+	{Name: "query_chain", Params: []string{"i32"}, Results: []string{"i32"}},
+}
+
+// SupportedImports returns the names and signatures of all host functions contracts can import
+// from the "env" module
+func SupportedImports() []types.FunctionSignature {
+	res := make([]types.FunctionSignature, len(supportedImports))
+	copy(res, supportedImports)
+	return res
+}
@@ -0,0 +1,34 @@
+package cosmwasm
+
+import (
+	"io/ioutil"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/CosmWasm/go-cosmwasm/types"
+)
+
+func TestSupportedImports(t *testing.T) {
+	supported := make(map[string]types.FunctionSignature)
+	for _, sig := range SupportedImports() {
+		supported[sig.Name] = sig
+	}
+	assert.Len(t, supported, 8)
+
+	// everything the test contracts import is in the list, with the same signature
+	for _, name := range []string{"hackatom.wasm", "queue.wasm", "reflect.wasm"} {
+		code, err := ioutil.ReadFile("./api/testdata/" + name)
+		require.NoError(t, err)
+		module, err := parseWasm(code)
+		require.NoError(t, err)
+		for _, imp := range module.imports {
+			assert.Equal(t, importModule, imp.Module)
+			sig, ok := supported[imp.Name]
+			require.True(t, ok, "%s imports unknown %s", name, imp.Name)
+			ft := module.types[imp.TypeIndex]
+			assert.Equal(t, sig, types.FunctionSignature{Name: imp.Name, Params: ft.Params, Results: ft.Results})
+		}
+	}
+}
@@ -0,0 +1,157 @@
+package cosmwasm
+
+import (
+	dbm "github.com/tendermint/tm-db"
+
+	"github.com/CosmWasm/go-cosmwasm/types"
+)
+
+// hostInstrument observes the host function calls of a contract for profiling and logging.
+// The store, api and querier of a call are only wrapped if one of them is enabled.
+type hostInstrument struct {
+	meter    GasMeter
+	profiler HostGasProfiler
+	logger   HostLogger
+}
+
+// hostCall measures a single host function call
+type hostCall struct {
+	hostInstrument
+	function   string
+	gasMeter   GasMeter
+	gasBefore  uint64
+	inputSize  int
+	outputSize int
+	// cost is gas reported by the callback itself rather than consumed on the gas meter
+	cost uint64
+}
+
+// start begins measuring the gas consumed on gasMeter, which the callbacks use to charge the call.
+// done must be deferred right after.
+func (ins hostInstrument) start(function string, gasMeter GasMeter, inputSize int) *hostCall {
+	return &hostCall{
+		hostInstrument: ins,
+		function:       function,
+		gasMeter:       gasMeter,
+		gasBefore:      gasMeter.GasConsumed(),
+		inputSize:      inputSize,
+	}
+}
+
+func (c *hostCall) done() {
+	gas := c.gasMeter.GasConsumed() - c.gasBefore + c.cost
+	if c.profiler != nil {
+		c.profiler.ProfileHostGas(c.function, gas)
+	}
+	if c.logger != nil {
+		c.logger.Debug("host call", "function", c.function, "input_bytes", c.inputSize, "output_bytes", c.outputSize, "gas", gas)
+	}
+}
+
+func (ins hostInstrument) instrumentAPI(goapi GoAPI) GoAPI {
+	return GoAPI{
+		HumanAddress: func(canon []byte) (string, uint64, error) {
+			call := ins.start("humanize_address", ins.meter, len(canon))
+			defer call.done()
+			human, cost, err := goapi.HumanAddress(canon)
+			call.outputSize, call.cost = len(human), cost
+			return human, cost, err
+		},
+		CanonicalAddress: func(human string) ([]byte, uint64, error) {
+			call := ins.start("canonicalize_address", ins.meter, len(human))
+			defer call.done()
+			canon, cost, err := goapi.CanonicalAddress(human)
+			call.outputSize, call.cost = len(canon), cost
+			return canon, cost, err
+		},
+	}
+}
+
+type instrumentedStore struct {
+	KVStore
+	hostInstrument
+}
+
+func (s instrumentedStore) Get(key []byte) []byte {
+	call := s.start("db_read", s.meter, len(key))
+	defer call.done()
+	value := s.KVStore.Get(key)
+	call.outputSize = len(value)
+	return value
+}
+
+func (s instrumentedStore) Set(key, value []byte) {
+	call := s.start("db_write", s.meter, len(key)+len(value))
+	defer call.done()
+	s.KVStore.Set(key, value)
+}
+
+func (s instrumentedStore) Delete(key []byte) {
+	call := s.start("db_remove", s.meter, len(key))
+	defer call.done()
+	s.KVStore.Delete(key)
+}
+
+func (s instrumentedStore) Iterator(start, end []byte) dbm.Iterator {
+	call := s.start("db_scan", s.meter, len(start)+len(end))
+	defer call.done()
+	return &instrumentedIterator{Iterator: s.KVStore.Iterator(start, end), hostInstrument: s.hostInstrument}
+}
+
+func (s instrumentedStore) ReverseIterator(start, end []byte) dbm.Iterator {
+	call := s.start("db_scan", s.meter, len(start)+len(end))
+	defer call.done()
+	return &instrumentedIterator{Iterator: s.KVStore.ReverseIterator(start, end), hostInstrument: s.hostInstrument}
+}
+
+// instrumentedIterator reports one db_next per key, which is the only import using the iterator.
+// The db_next callback reads Key and Value and then calls Next, so a call starts at the first of these
+// and is reported by Next with the size of the key and value. Valid is not measured, as it charges
+// no gas and the final db_next at the end of the iteration returns nothing.
+type instrumentedIterator struct {
+	dbm.Iterator
+	hostInstrument
+	call *hostCall
+}
+
+func (it *instrumentedIterator) startNext() *hostCall {
+	if it.call == nil {
+		it.call = it.start("db_next", it.meter, 0)
+	}
+	return it.call
+}
+
+func (it *instrumentedIterator) Key() []byte {
+	call := it.startNext()
+	key := it.Iterator.Key()
+	call.outputSize += len(key)
+	return key
+}
+
+func (it *instrumentedIterator) Value() []byte {
+	call := it.startNext()
+	value := it.Iterator.Value()
+	call.outputSize += len(value)
+	return value
+}
+
+func (it *instrumentedIterator) Next() {
+	call := it.startNext()
+	defer call.done()
+	it.call = nil
+	it.Iterator.Next()
+}
+
+// instrumentedQuerier measures the gas of queries on the querier, like the query_chain callback
+type instrumentedQuerier struct {
+	Querier
+	hostInstrument
+}
+
+func (q instrumentedQuerier) Query(request types.QueryRequest, gasLimit uint64) ([]byte, error) {
+	call := q.start("query_chain", q.Querier, 0)
+	defer call.done()
+	res, err := q.Querier.Query(request, gasLimit)
+	call.outputSize = len(res)
+	return res, err
+}
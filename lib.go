@@ -1,8 +1,13 @@
 package cosmwasm
 
 import (
+	"crypto/sha256"
 	"encoding/json"
 	"fmt"
+	"io"
+	"io/ioutil"
+	"sync"
+	"time"
 
 	"github.com/CosmWasm/go-cosmwasm/api"
 	"github.com/CosmWasm/go-cosmwasm/types"
@@ -11,6 +16,13 @@ import (
 // CodeID represents an ID for a given wasm code blob, must be generated from this library
 type CodeID []byte
 
+// Checksum returns the CodeID the VM assigns to the given wasm code, which is its sha256 hash.
+// This does not validate or store the code.
+func Checksum(wasm WasmCode) CodeID {
+	hash := sha256.Sum256(wasm)
+	return hash[:]
+}
+
 // WasmCode is an alias for raw bytes of the wasm compiled code
 type WasmCode []byte
 
@@ -30,7 +42,17 @@ type GasMeter = api.GasMeter
 // You should create an instance with it's own subdirectory to manage state inside,
 // and call it for all cosmwasm code related actions.
 type Wasmer struct {
-	cache api.Cache
+	cache  api.Cache
+	config types.VMConfig
+	logger HostLogger
+
+	countsMu        sync.Mutex
+	executionCounts map[string]uint64
+	compileMetrics  CompileMetrics
+
+	// codeInfos caches the result of GetCodeInfo. Stored code never changes, so it is never invalidated.
+	codeInfosMu sync.RWMutex
+	codeInfos   map[string]types.CodeInfo
 }
 
 // NewWasmer creates an new binding, with the given dataDir where
@@ -38,12 +60,28 @@ type Wasmer struct {
 // cacheSize sets the size of an optional in-memory LRU cache for prepared VMs.
 // They allow popular contracts to be executed very rapidly (no loading overhead),
 // but require ~32-64MB each in memory usage.
+// Nothing but the code passed to Create is stored in dataDir, unless the health check
+// is enabled (see HealthCheck), which stores its probe contract there as well.
 func NewWasmer(dataDir string, supportedFeatures string, cacheSize uint64) (*Wasmer, error) {
-	cache, err := api.InitCache(dataDir, supportedFeatures, cacheSize)
+	return NewWasmerWithConfig(types.VMConfig{
+		DataDir:           dataDir,
+		SupportedFeatures: supportedFeatures,
+		CacheSize:         cacheSize,
+	})
+}
+
+// NewWasmerWithConfig creates an new binding like NewWasmer, but allows setting
+// all the other options of VMConfig as well.
+func NewWasmerWithConfig(config types.VMConfig) (*Wasmer, error) {
+	cache, err := api.InitCacheWithOptions(api.CacheOptions{
+		DataDir:           config.DataDir,
+		SupportedFeatures: config.SupportedFeatures,
+		CacheSize:         config.CacheSize,
+	})
 	if err != nil {
 		return nil, err
 	}
-	return &Wasmer{cache: cache}, nil
+	return &Wasmer{cache: cache, config: config}, nil
 }
 
 // Cleanup should be called when no longer using this to free resources on the rust-side
@@ -62,9 +100,34 @@ func (w *Wasmer) Cleanup() {
 //
 // TODO: return gas cost? Add gas limit??? there is no metering here...
 func (w *Wasmer) Create(code WasmCode) (CodeID, error) {
+	if err := w.checkWasm(code); err != nil {
+		return nil, err
+	}
+	start := time.Now()
+	defer w.countCompile(start)
 	return api.Create(w.cache, code)
 }
 
+// CreateFromReader is Create with the code read from r, e.g. a file, and returns the same CodeID.
+// The VM needs the whole code to compile it, so it is read into memory first. It saves callers
+// from buffering the code themselves before passing it on. At most VMConfig.MaxCodeSize bytes are
+// read, larger code fails with types.ErrCodeTooLarge.
+func (w *Wasmer) CreateFromReader(r io.Reader) (CodeID, error) {
+	max := w.config.MaxCodeSize
+	if max <= 0 {
+		max = types.DefaultMaxCodeSize
+	}
+	// read one byte more than allowed to tell code of exactly the max size from larger code
+	code, err := ioutil.ReadAll(io.LimitReader(r, int64(max)+1))
+	if err != nil {
+		return nil, fmt.Errorf("reading wasm code: %w", err)
+	}
+	if len(code) > max {
+		return nil, fmt.Errorf("%w: more than %d bytes", types.ErrCodeTooLarge, max)
+	}
+	return w.Create(code)
+}
+
 // GetCode will load the original wasm code for the given code id.
 // This will only succeed if that code id was previously returned from
 // a call to Create.
@@ -73,13 +136,100 @@ func (w *Wasmer) Create(code WasmCode) (CodeID, error) {
 // and the larger binary blobs (wasm and pre-compiles) are all managed by the
 // rust library
 func (w *Wasmer) GetCode(code CodeID) (WasmCode, error) {
+	if err := validateChecksum(code); err != nil {
+		return nil, err
+	}
 	return api.GetCode(w.cache, code)
 }
 
+// WriteCodeTo writes the original wasm code for the given code id to out.
+// Unlike GetCode, this does not make a copy of the code in go memory, which
+// makes it better suited for exporting many or very large contracts.
+func (w *Wasmer) WriteCodeTo(code CodeID, out io.Writer) (int, error) {
+	if err := validateChecksum(code); err != nil {
+		return 0, err
+	}
+	return api.WriteCodeTo(w.cache, code, out)
+}
+
+// GetCodeInfo returns metadata about the code with the given code id, like its size
+// and the result of its static analysis. This does not copy the code into go memory,
+// so it is cheaper than calling GetCode to inspect the code. The result is cached, as
+// Instantiate checks it on every call.
+func (w *Wasmer) GetCodeInfo(code CodeID) (types.CodeInfo, error) {
+	if err := validateChecksum(code); err != nil {
+		return types.CodeInfo{}, err
+	}
+	w.codeInfosMu.RLock()
+	info, ok := w.codeInfos[string(code)]
+	w.codeInfosMu.RUnlock()
+	if ok {
+		return copyCodeInfo(info), nil
+	}
+
+	err := api.ReadCode(w.cache, code, func(wasm []byte) error {
+		module, err := parseWasm(wasm)
+		if err != nil {
+			return err
+		}
+		info.AnalysisReport = analyzeModule(module)
+		info.Size = uint64(len(wasm))
+		return nil
+	})
+	if err != nil {
+		return types.CodeInfo{}, err
+	}
+
+	w.codeInfosMu.Lock()
+	if w.codeInfos == nil {
+		w.codeInfos = make(map[string]types.CodeInfo)
+	}
+	w.codeInfos[string(code)] = info
+	w.codeInfosMu.Unlock()
+	return copyCodeInfo(info), nil
+}
+
+// copyCodeInfo copies the slices of a cached CodeInfo, so callers cannot modify the cache
+func copyCodeInfo(info types.CodeInfo) types.CodeInfo {
+	if info.RequiredFeaturesSet != nil {
+		info.RequiredFeaturesSet = append([]string{}, info.RequiredFeaturesSet...)
+	}
+	return info
+}
+
+// AnalyzeCode returns the result of the static analysis of the code with the given code id
+func (w *Wasmer) AnalyzeCode(code CodeID) (*types.AnalysisReport, error) {
+	info, err := w.GetCodeInfo(code)
+	if err != nil {
+		return nil, err
+	}
+	return &info.AnalysisReport, nil
+}
+
+// DescribeExports returns the signatures of all functions exported by the code with the given code id.
+// This is meant for debugging contracts that fail with signature mismatches.
+func (w *Wasmer) DescribeExports(code CodeID) ([]types.FunctionSignature, error) {
+	if err := validateChecksum(code); err != nil {
+		return nil, err
+	}
+	var res []types.FunctionSignature
+	err := api.ReadCode(w.cache, code, func(wasm []byte) error {
+		module, err := parseWasm(wasm)
+		if err != nil {
+			return err
+		}
+		res, err = exportedFunctions(module)
+		return err
+	})
+	return res, err
+}
+
 // Instantiate will create a new contract based on the given codeID.
 // We can set the initMsg (contract "genesis") here, and it then receives
 // an account and address and can be invoked (Execute) many times.
 //
+// Instantiation fails if the code requires features that are not in the supported features of this Wasmer.
+//
 // Storage should be set with a PrefixedKVStore that this code can safely access.
 //
 // Under the hood, we may recompile the wasm, use a cached native compile, or even use a cached instance
@@ -94,12 +244,45 @@ func (w *Wasmer) Instantiate(
 	gasMeter GasMeter,
 	gasLimit uint64,
 ) (*types.InitResponse, uint64, error) {
-	paramBin, err := json.Marshal(env)
+	if err := validateChecksum(code); err != nil {
+		return nil, 0, err
+	}
+	if err := w.checkGasMeter(gasMeter); err != nil {
+		return nil, 0, err
+	}
+	if err := w.checkMessageSize(initMsg); err != nil {
+		return nil, 0, err
+	}
+	if err := checkMsgObject(initMsg); err != nil {
+		return nil, 0, err
+	}
+	if err := checkFunds(env.Message.SentFunds); err != nil {
+		return nil, 0, err
+	}
+	if err := w.checkFeatures(code); err != nil {
+		return nil, 0, err
+	}
+	paramBin, err := w.serializeEnv(env)
 	if err != nil {
 		return nil, 0, err
 	}
-	data, gasUsed, err := api.Instantiate(w.cache, code, paramBin, initMsg, &gasMeter, store, &goapi, &querier, gasLimit)
+	if err := w.checkMessageSize(paramBin); err != nil {
+		return nil, 0, err
+	}
+	inputGas, err := w.checkMsgInputs(gasMeter, gasLimit, paramBin, initMsg)
+	if err != nil {
+		return nil, inputGas, err
+	}
+	w.countExecution(code)
+	call := w.newCallContext(store, goapi, querier, gasMeter)
+	gasBefore := gasMeter.GasConsumed()
+	data, gasUsed, err := api.Instantiate(w.cache, code, paramBin, initMsg, &gasMeter, call.store, &call.api, &call.querier, gasLimit-inputGas)
+	gasUsed += inputGas
+	chargeWasmGas(gasMeter, gasBefore, gasUsed)
 	if err != nil {
+		return nil, gasUsed, call.panics.check(err)
+	}
+	if err := w.checkResultSize(data); err != nil {
 		return nil, gasUsed, err
 	}
 
@@ -109,7 +292,10 @@ func (w *Wasmer) Instantiate(
 		return nil, gasUsed, err
 	}
 	if resp.Err != nil {
-		return nil, gasUsed, fmt.Errorf("%v", resp.Err)
+		return nil, gasUsed, types.ContractError{Err: *resp.Err}
+	}
+	if err := w.checkMessageCount(resp.Ok.Messages); err != nil {
+		return nil, gasUsed, err
 	}
 	return resp.Ok, gasUsed, nil
 }
@@ -130,12 +316,42 @@ func (w *Wasmer) Execute(
 	gasMeter GasMeter,
 	gasLimit uint64,
 ) (*types.HandleResponse, uint64, error) {
-	paramBin, err := json.Marshal(env)
+	if err := validateChecksum(code); err != nil {
+		return nil, 0, err
+	}
+	if err := w.checkGasMeter(gasMeter); err != nil {
+		return nil, 0, err
+	}
+	if err := w.checkMessageSize(executeMsg); err != nil {
+		return nil, 0, err
+	}
+	if err := checkMsgObject(executeMsg); err != nil {
+		return nil, 0, err
+	}
+	if err := checkFunds(env.Message.SentFunds); err != nil {
+		return nil, 0, err
+	}
+	paramBin, err := w.serializeEnv(env)
 	if err != nil {
 		return nil, 0, err
 	}
-	data, gasUsed, err := api.Handle(w.cache, code, paramBin, executeMsg, &gasMeter, store, &goapi, &querier, gasLimit)
+	if err := w.checkMessageSize(paramBin); err != nil {
+		return nil, 0, err
+	}
+	inputGas, err := w.checkMsgInputs(gasMeter, gasLimit, paramBin, executeMsg)
+	if err != nil {
+		return nil, inputGas, err
+	}
+	w.countExecution(code)
+	call := w.newCallContext(store, goapi, querier, gasMeter)
+	gasBefore := gasMeter.GasConsumed()
+	data, gasUsed, err := api.Handle(w.cache, code, paramBin, executeMsg, &gasMeter, call.store, &call.api, &call.querier, gasLimit-inputGas)
+	gasUsed += inputGas
+	chargeWasmGas(gasMeter, gasBefore, gasUsed)
 	if err != nil {
+		return nil, gasUsed, call.panics.check(err)
+	}
+	if err := w.checkResultSize(data); err != nil {
 		return nil, gasUsed, err
 	}
 
@@ -145,7 +361,10 @@ func (w *Wasmer) Execute(
 		return nil, gasUsed, err
 	}
 	if resp.Err != nil {
-		return nil, gasUsed, fmt.Errorf("%v", resp.Err)
+		return nil, gasUsed, types.ContractError{Err: *resp.Err}
+	}
+	if err := w.checkMessageCount(resp.Ok.Messages); err != nil {
+		return nil, gasUsed, err
 	}
 	return resp.Ok, gasUsed, nil
 }
@@ -162,7 +381,7 @@ func (w *Wasmer) Query(
 	gasMeter GasMeter,
 	gasLimit uint64,
 ) ([]byte, uint64, error) {
-	data, gasUsed, err := api.Query(w.cache, code, queryMsg, &gasMeter, store, &goapi, &querier, gasLimit)
+	data, gasUsed, err := w.query(code, queryMsg, store, goapi, querier, gasMeter, gasLimit)
 	if err != nil {
 		return nil, gasUsed, err
 	}
@@ -173,15 +392,88 @@ func (w *Wasmer) Query(
 		return nil, gasUsed, err
 	}
 	if resp.Err != nil {
-		return nil, gasUsed, fmt.Errorf("%v", resp.Err)
+		return nil, gasUsed, types.ContractError{Err: *resp.Err}
+	}
+	if err := w.checkQueryResult(resp.Ok); err != nil {
+		return nil, gasUsed, err
 	}
 	return resp.Ok, gasUsed, nil
 }
 
+// QueryRaw is like Query, but returns the result envelope of the contract (`{"Ok":...}` or `{"Err":...}`)
+// as is, along with the kind of result. If the query failed outside of the contract (e.g. out of gas or
+// unknown code), the kind is types.QueryResultSystemError and the cause is returned as error.
+func (w *Wasmer) QueryRaw(
+	code CodeID,
+	queryMsg []byte,
+	store KVStore,
+	goapi GoAPI,
+	querier Querier,
+	gasMeter GasMeter,
+	gasLimit uint64,
+) ([]byte, types.QueryResultKind, uint64, error) {
+	data, gasUsed, err := w.query(code, queryMsg, store, goapi, querier, gasMeter, gasLimit)
+	if err != nil {
+		return nil, types.QueryResultSystemError, gasUsed, err
+	}
+
+	var resp types.QueryResponse
+	err = json.Unmarshal(data, &resp)
+	if err != nil {
+		return nil, types.QueryResultSystemError, gasUsed, err
+	}
+	if resp.Err != nil {
+		return data, types.QueryResultContractError, gasUsed, nil
+	}
+	if err := w.checkQueryResult(resp.Ok); err != nil {
+		return nil, types.QueryResultSystemError, gasUsed, err
+	}
+	return data, types.QueryResultOk, gasUsed, nil
+}
+
+// query runs a query and returns the serialized types.QueryResponse of the contract
+func (w *Wasmer) query(
+	code CodeID,
+	queryMsg []byte,
+	store KVStore,
+	goapi GoAPI,
+	querier Querier,
+	gasMeter GasMeter,
+	gasLimit uint64,
+) ([]byte, uint64, error) {
+	if err := validateChecksum(code); err != nil {
+		return nil, 0, err
+	}
+	if err := w.checkGasMeter(gasMeter); err != nil {
+		return nil, 0, err
+	}
+	if err := w.checkMessageSize(queryMsg); err != nil {
+		return nil, 0, err
+	}
+	inputGas, err := w.checkInputs(gasMeter, gasLimit, queryMsg)
+	if err != nil {
+		return nil, inputGas, err
+	}
+	store = ReadOnlyKVStore{store}
+	w.countExecution(code)
+	call := w.newCallContext(store, goapi, querier, gasMeter)
+	gasBefore := gasMeter.GasConsumed()
+	data, gasUsed, err := api.Query(w.cache, code, queryMsg, &gasMeter, call.store, &call.api, &call.querier, gasLimit-inputGas)
+	gasUsed += inputGas
+	chargeWasmGas(gasMeter, gasBefore, gasUsed)
+	if err != nil {
+		return nil, gasUsed, call.panics.check(err)
+	}
+	if err := w.checkResultSize(data); err != nil {
+		return nil, gasUsed, err
+	}
+	return data, gasUsed, nil
+}
+
 // Migrate will migrate an existing contract to a new code binary.
 // This takes storage of the data from the original contract and the CodeID of the new contract that should
 // replace it. This allows it to run a migration step if needed, or return an error if unable to migrate
-// the given data.
+// the given data. Migrating to a code that does not export migrate fails early with types.ErrNoMigrateEntryPoint.
 //
 // MigrateMsg has some data on how to perform the migration.
 func (w *Wasmer) Migrate(
@@ -194,12 +486,42 @@ func (w *Wasmer) Migrate(
 	gasMeter GasMeter,
 	gasLimit uint64,
 ) (*types.MigrateResponse, uint64, error) {
-	paramBin, err := json.Marshal(env)
+	if err := validateChecksum(code); err != nil {
+		return nil, 0, err
+	}
+	if err := w.checkGasMeter(gasMeter); err != nil {
+		return nil, 0, err
+	}
+	if err := w.checkMessageSize(migrateMsg); err != nil {
+		return nil, 0, err
+	}
+	if err := checkMsgObject(migrateMsg); err != nil {
+		return nil, 0, err
+	}
+	if err := w.checkMigrate(code); err != nil {
+		return nil, 0, err
+	}
+	paramBin, err := w.serializeEnv(env)
 	if err != nil {
 		return nil, 0, err
 	}
-	data, gasUsed, err := api.Migrate(w.cache, code, paramBin, migrateMsg, &gasMeter, store, &goapi, &querier, gasLimit)
+	if err := w.checkMessageSize(paramBin); err != nil {
+		return nil, 0, err
+	}
+	inputGas, err := w.checkMsgInputs(gasMeter, gasLimit, paramBin, migrateMsg)
+	if err != nil {
+		return nil, inputGas, err
+	}
+	w.countExecution(code)
+	call := w.newCallContext(store, goapi, querier, gasMeter)
+	gasBefore := gasMeter.GasConsumed()
+	data, gasUsed, err := api.Migrate(w.cache, code, paramBin, migrateMsg, &gasMeter, call.store, &call.api, &call.querier, gasLimit-inputGas)
+	gasUsed += inputGas
+	chargeWasmGas(gasMeter, gasBefore, gasUsed)
 	if err != nil {
+		return nil, gasUsed, call.panics.check(err)
+	}
+	if err := w.checkResultSize(data); err != nil {
 		return nil, gasUsed, err
 	}
 
@@ -209,7 +531,10 @@ func (w *Wasmer) Migrate(
 		return nil, gasUsed, err
 	}
 	if resp.Err != nil {
-		return nil, gasUsed, fmt.Errorf("%v", resp.Err)
+		return nil, gasUsed, types.ContractError{Err: *resp.Err}
+	}
+	if err := w.checkMessageCount(resp.Ok.Messages); err != nil {
+		return nil, gasUsed, err
 	}
 	return resp.Ok, gasUsed, nil
 }
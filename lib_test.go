@@ -0,0 +1,301 @@
+package cosmwasm
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/CosmWasm/go-cosmwasm/types"
+)
+
+func withWasmer(t *testing.T, config types.VMConfig) (*Wasmer, func()) {
+	tmpdir, err := ioutil.TempDir("", "go-cosmwasm")
+	require.NoError(t, err)
+	config.DataDir = tmpdir
+	if config.SupportedFeatures == "" {
+		config.SupportedFeatures = "staking"
+	}
+	wasmer, err := NewWasmerWithConfig(config)
+	require.NoError(t, err)
+
+	cleanup := func() {
+		os.RemoveAll(tmpdir)
+		wasmer.Cleanup()
+	}
+	return wasmer, cleanup
+}
+
+func createTestCode(t *testing.T, wasmer *Wasmer, name string) (CodeID, []byte) {
+	wasm, err := ioutil.ReadFile("./api/testdata/" + name)
+	require.NoError(t, err)
+	id, err := wasmer.Create(wasm)
+	require.NoError(t, err)
+	return id, wasm
+}
+
+func TestGetCodeInfo(t *testing.T) {
+	wasmer, cleanup := withWasmer(t, types.VMConfig{})
+	defer cleanup()
+
+	id, wasm := createTestCode(t, wasmer, "hackatom.wasm")
+	info, err := wasmer.GetCodeInfo(id)
+	require.NoError(t, err)
+	assert.Equal(t, uint64(len(wasm)), info.Size)
+	assert.False(t, info.HasIBCEntryPoints)
+	assert.Equal(t, "", info.RequiredFeatures)
+	assert.Equal(t, uint32(3), info.InterfaceVersion)
+
+	id, _ = createTestCode(t, wasmer, "reflect.wasm")
+	report, err := wasmer.AnalyzeCode(id)
+	require.NoError(t, err)
+	assert.Equal(t, "staking", report.RequiredFeatures)
+	assert.Equal(t, []string{"staking"}, report.RequiredFeaturesSet)
+
+	_, err = wasmer.GetCodeInfo(CodeID("foobar"))
+	require.Error(t, err)
+	assert.True(t, errors.Is(err, types.ErrInvalidChecksum))
+	_, err = wasmer.AnalyzeCode(CodeID("foobar"))
+	assert.True(t, errors.Is(err, types.ErrInvalidChecksum))
+	_, _, err = wasmer.Execute(CodeID("foobar"), mockEnv("creator"), []byte(`{}`), newMemStore(), mockAPI(), nil, &readOnlyMeter{}, 100000000)
+	assert.True(t, errors.Is(err, types.ErrInvalidChecksum))
+}
+
+func TestGetCodeInfoCached(t *testing.T) {
+	wasmer, cleanup := withWasmer(t, types.VMConfig{})
+	defer cleanup()
+
+	id, _ := createTestCode(t, wasmer, "reflect.wasm")
+	info, err := wasmer.GetCodeInfo(id)
+	require.NoError(t, err)
+	assert.Len(t, wasmer.codeInfos, 1)
+
+	// modifying the result does not affect the cache
+	info.RequiredFeaturesSet[0] = "foo"
+	again, err := wasmer.GetCodeInfo(id)
+	require.NoError(t, err)
+	assert.Equal(t, []string{"staking"}, again.RequiredFeaturesSet)
+
+	// failures are not cached
+	_, err = wasmer.GetCodeInfo(Checksum([]byte("unknown")))
+	require.Error(t, err)
+	assert.Len(t, wasmer.codeInfos, 1)
+}
+
+func TestCodeNotFound(t *testing.T) {
+	wasmer, cleanup := withWasmer(t, types.VMConfig{})
+	defer cleanup()
+
+	id := Checksum([]byte("unknown"))
+	_, err := wasmer.GetCode(id)
+	assert.True(t, errors.Is(err, types.ErrCodeNotFound))
+	_, err = wasmer.GetCodeInfo(id)
+	assert.True(t, errors.Is(err, types.ErrCodeNotFound))
+	_, _, err = wasmer.Instantiate(id, mockEnv("creator"), []byte(`{}`), newMemStore(), mockAPI(), nil, &readOnlyMeter{}, 100000000)
+	assert.True(t, errors.Is(err, types.ErrCodeNotFound))
+	_, _, err = wasmer.Execute(id, mockEnv("creator"), []byte(`{}`), newMemStore(), mockAPI(), nil, &readOnlyMeter{}, 100000000)
+	assert.True(t, errors.Is(err, types.ErrCodeNotFound))
+	_, _, err = wasmer.Query(id, []byte(`{}`), newMemStore(), mockAPI(), nil, &readOnlyMeter{}, 100000000)
+	assert.True(t, errors.Is(err, types.ErrCodeNotFound))
+}
+
+func TestInstantiateChecksFeatures(t *testing.T) {
+	tmpdir, err := ioutil.TempDir("", "go-cosmwasm")
+	require.NoError(t, err)
+	defer os.RemoveAll(tmpdir)
+
+	// store the code with a VM that supports staking
+	wasmer, err := NewWasmer(tmpdir, "staking", 0)
+	require.NoError(t, err)
+	id, _ := createTestCode(t, wasmer, "reflect.wasm")
+	wasmer.Cleanup()
+
+	// and reuse the same directory with a VM that doesn't
+	wasmer, err = NewWasmer(tmpdir, "iterator", 0)
+	require.NoError(t, err)
+	defer wasmer.Cleanup()
+
+	_, _, err = wasmer.Instantiate(id, types.Env{}, []byte(`{}`), nil, GoAPI{}, nil, &readOnlyMeter{}, 100000000)
+	require.Error(t, err)
+	assert.Equal(t, "contract requires unavailable features: staking", err.Error())
+	// the analysis is only done once per code
+	assert.Len(t, wasmer.codeInfos, 1)
+}
+
+func TestDescribeExports(t *testing.T) {
+	wasmer, cleanup := withWasmer(t, types.VMConfig{})
+	defer cleanup()
+
+	id, _ := createTestCode(t, wasmer, "queue.wasm")
+	sigs, err := wasmer.DescribeExports(id)
+	require.NoError(t, err)
+	var names []string
+	for _, sig := range sigs {
+		names = append(names, sig.Name)
+	}
+	assert.Equal(t, []string{"init", "handle", "query", "allocate", "deallocate", "cosmwasm_vm_version_3"}, names)
+}
+
+func TestQueryRaw(t *testing.T) {
+	wasmer, cleanup := withWasmer(t, types.VMConfig{})
+	defer cleanup()
+	id, _ := createTestCode(t, wasmer, "hackatom.wasm")
+
+	store := newMemStore()
+	msg := []byte(`{"verifier": "fred", "beneficiary": "bob"}`)
+	_, _, err := wasmer.Instantiate(id, mockEnv("creator"), msg, store, mockAPI(), nil, &readOnlyMeter{}, 100000000)
+	require.NoError(t, err)
+
+	res, kind, _, err := wasmer.QueryRaw(id, []byte(`{"verifier":{}}`), store, mockAPI(), nil, &readOnlyMeter{}, 100000000)
+	require.NoError(t, err)
+	assert.Equal(t, types.QueryResultOk, kind)
+	assert.Equal(t, `{"Ok":"eyJ2ZXJpZmllciI6ImZyZWQifQ=="}`, string(res))
+
+	res, kind, _, err = wasmer.QueryRaw(id, []byte(`{"foo":{}}`), store, mockAPI(), nil, &readOnlyMeter{}, 100000000)
+	require.NoError(t, err)
+	assert.Equal(t, types.QueryResultContractError, kind)
+	assert.Contains(t, string(res), `"Err"`)
+
+	res, kind, _, err = wasmer.QueryRaw(id, []byte(`{"verifier":{}}`), store, mockAPI(), nil, &readOnlyMeter{}, 1000)
+	require.Error(t, err)
+	assert.Equal(t, types.QueryResultSystemError, kind)
+	assert.Nil(t, res)
+}
+
+func TestChecksum(t *testing.T) {
+	wasmer, cleanup := withWasmer(t, types.VMConfig{})
+	defer cleanup()
+
+	id, wasm := createTestCode(t, wasmer, "hackatom.wasm")
+	assert.Equal(t, id, Checksum(wasm))
+	assert.Len(t, Checksum(nil), 32)
+}
+
+func TestMaxResultSize(t *testing.T) {
+	wasmer, cleanup := withWasmer(t, types.VMConfig{MaxResultSize: 16})
+	defer cleanup()
+	id, _ := createTestCode(t, wasmer, "hackatom.wasm")
+
+	store := newMemStore()
+	msg := []byte(`{"verifier": "fred", "beneficiary": "bob"}`)
+	_, _, err := wasmer.Instantiate(id, mockEnv("creator"), msg, store, mockAPI(), nil, &readOnlyMeter{}, 100000000)
+	require.Error(t, err)
+	assert.True(t, errors.Is(err, types.ErrResultTooLarge))
+}
+
+func TestMaxMessageSizeChecksEnv(t *testing.T) {
+	wasmer, cleanup := withWasmer(t, types.VMConfig{MaxMessageSize: 256})
+	defer cleanup()
+	id, _ := createTestCode(t, wasmer, "hackatom.wasm")
+
+	// the msg is small, but the env is not
+	store := newMemStore()
+	msg := []byte(`{"verifier": "fred", "beneficiary": "bob"}`)
+	env := mockEnv(types.HumanAddress(strings.Repeat("x", 256)))
+	_, gasUsed, err := wasmer.Instantiate(id, env, msg, store, mockAPI(), nil, &readOnlyMeter{}, 100000000)
+	require.Error(t, err)
+	assert.True(t, errors.Is(err, types.ErrMessageTooLarge))
+	assert.Equal(t, uint64(0), gasUsed)
+	assert.Equal(t, uint64(0), wasmer.GetExecutionCounts()[string(id)])
+
+	_, _, err = wasmer.Instantiate(id, mockEnv("creator"), msg, store, mockAPI(), nil, &readOnlyMeter{}, 100000000)
+	require.NoError(t, err)
+}
+
+func TestContractError(t *testing.T) {
+	wasmer, cleanup := withWasmer(t, types.VMConfig{})
+	defer cleanup()
+	id, _ := createTestCode(t, wasmer, "hackatom.wasm")
+
+	store := newMemStore()
+	msg := []byte(`{"verifier": "fred", "beneficiary": "bob"}`)
+	_, _, err := wasmer.Instantiate(id, mockEnv("creator"), msg, store, mockAPI(), nil, &readOnlyMeter{}, 100000000)
+	require.NoError(t, err)
+
+	// only the verifier may release
+	_, _, err = wasmer.Execute(id, mockEnv("mallory"), []byte(`{"release":{}}`), store, mockAPI(), nil, &readOnlyMeter{}, 100000000)
+	var contractErr types.ContractError
+	require.True(t, errors.As(err, &contractErr))
+	assert.NotNil(t, contractErr.Err.Unauthorized)
+	assert.Equal(t, "unauthorized", err.Error())
+}
+
+func TestMigrateWithoutEntryPoint(t *testing.T) {
+	wasmer, cleanup := withWasmer(t, types.VMConfig{})
+	defer cleanup()
+	hackatom, _ := createTestCode(t, wasmer, "hackatom.wasm")
+	queue, _ := createTestCode(t, wasmer, "queue.wasm")
+
+	info, err := wasmer.GetCodeInfo(hackatom)
+	require.NoError(t, err)
+	assert.True(t, info.HasMigrateEntryPoint)
+
+	store := newMemStore()
+	_, _, err = wasmer.Migrate(queue, mockEnv("creator"), []byte(`{}`), store, mockAPI(), nil, &readOnlyMeter{}, 100000000)
+	require.Error(t, err)
+	assert.True(t, errors.Is(err, types.ErrNoMigrateEntryPoint))
+	assert.Equal(t, fmt.Sprintf("target code %X has no migrate entry point", queue), err.Error())
+}
+
+type failingReader struct{}
+
+func (failingReader) Read([]byte) (int, error) {
+	return 0, errors.New("disk on fire")
+}
+
+func TestCreateFromReader(t *testing.T) {
+	wasmer, cleanup := withWasmer(t, types.VMConfig{})
+	defer cleanup()
+
+	f, err := os.Open("./api/testdata/hackatom.wasm")
+	require.NoError(t, err)
+	defer f.Close()
+	id, err := wasmer.CreateFromReader(f)
+	require.NoError(t, err)
+
+	wasm, err := wasmer.GetCode(id)
+	require.NoError(t, err)
+	assert.Equal(t, Checksum(wasm), id)
+
+	_, err = wasmer.CreateFromReader(failingReader{})
+	require.Error(t, err)
+	assert.Equal(t, "reading wasm code: disk on fire", err.Error())
+}
+
+func TestCreateFromReaderMaxCodeSize(t *testing.T) {
+	wasm, err := ioutil.ReadFile("./api/testdata/hackatom.wasm")
+	require.NoError(t, err)
+
+	wasmer, cleanup := withWasmer(t, types.VMConfig{MaxCodeSize: len(wasm) - 1})
+	defer cleanup()
+	_, err = wasmer.CreateFromReader(bytes.NewReader(wasm))
+	assert.True(t, errors.Is(err, types.ErrCodeTooLarge))
+	assert.Equal(t, fmt.Sprintf("code exceeds max size: more than %d bytes", len(wasm)-1), err.Error())
+
+	// exactly the max size is fine
+	wasmer.config.MaxCodeSize = len(wasm)
+	_, err = wasmer.CreateFromReader(bytes.NewReader(wasm))
+	require.NoError(t, err)
+
+	// without a max size the default applies
+	wasmer.config.MaxCodeSize = 0
+	_, err = wasmer.CreateFromReader(io.LimitReader(zeroReader{}, types.DefaultMaxCodeSize+1))
+	assert.True(t, errors.Is(err, types.ErrCodeTooLarge))
+}
+
+// zeroReader reads an endless stream of zeros
+type zeroReader struct{}
+
+func (zeroReader) Read(p []byte) (int, error) {
+	for i := range p {
+		p[i] = 0
+	}
+	return len(p), nil
+}
@@ -0,0 +1,15 @@
+package cosmwasm
+
+// HostLogger receives a debug log entry for every host function call of a contract
+// (storage, address conversion and queries) with the function name, the byte sizes of
+// input and output and the gas charged. It is compatible with the tendermint logger.
+type HostLogger interface {
+	Debug(msg string, keyvals ...interface{})
+}
+
+// SetHostLogger enables logging of host function calls for all following contract calls.
+// Passing nil turns it off again, which is the default. This must not be called concurrently
+// with contract calls.
+func (w *Wasmer) SetHostLogger(logger HostLogger) {
+	w.logger = logger
+}
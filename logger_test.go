@@ -0,0 +1,65 @@
+package cosmwasm
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/CosmWasm/go-cosmwasm/types"
+)
+
+type logEntry struct {
+	msg     string
+	keyvals []interface{}
+}
+
+type recordingLogger struct {
+	entries []logEntry
+}
+
+func (l *recordingLogger) Debug(msg string, keyvals ...interface{}) {
+	l.entries = append(l.entries, logEntry{msg: msg, keyvals: keyvals})
+}
+
+func TestHostLogger(t *testing.T) {
+	sdk := &sdkMeter{}
+	logger := &recordingLogger{}
+	w := &Wasmer{}
+	w.SetHostLogger(logger)
+	store := chargingStore{memStore: newMemStore(), meter: sdk}
+	call := w.newCallContext(store, mockAPI(), nil, sdk)
+
+	call.store.Set([]byte("foo"), []byte("bar"))
+	call.store.Get([]byte("foo"))
+	_, _, err := call.api.HumanAddress(make([]byte, canonicalLength))
+	require.NoError(t, err)
+
+	assert.Equal(t, []logEntry{
+		{"host call", []interface{}{"function", "db_write", "input_bytes", 6, "output_bytes", 0, "gas", uint64(100)}},
+		{"host call", []interface{}{"function", "db_read", "input_bytes", 3, "output_bytes", 3, "gas", uint64(10)}},
+		{"host call", []interface{}{"function", "humanize_address", "input_bytes", 32, "output_bytes", 0, "gas", uint64(550)}},
+	}, logger.entries)
+
+	// iterating reports one db_next per key, with the gas charged for the key and value
+	w.config.GasConfig.IterNextCostPerByte = 1
+	logger.entries = nil
+	call = w.newCallContext(store, mockAPI(), nil, sdk)
+	it := call.store.Iterator(nil, nil)
+	for it.Valid() {
+		it.Key()
+		it.Value()
+		it.Next()
+	}
+	assert.Equal(t, []logEntry{
+		{"host call", []interface{}{"function", "db_scan", "input_bytes", 0, "output_bytes", 0, "gas", uint64(0)}},
+		{"host call", []interface{}{"function", "db_next", "input_bytes", 0, "output_bytes", 6, "gas", uint64(6)}},
+	}, logger.entries)
+	w.config.GasConfig = types.GasConfig{}
+
+	// no logger, no wrapping
+	w.SetHostLogger(nil)
+	call = w.newCallContext(store, mockAPI(), nil, sdk)
+	_, ok := call.store.(guardedStore).KVStore.(chargingStore)
+	assert.True(t, ok)
+}
@@ -0,0 +1,62 @@
+package cosmwasm
+
+import (
+	"encoding/hex"
+	"time"
+)
+
+// countExecution counts a call of any entry point of the given code
+func (w *Wasmer) countExecution(code CodeID) {
+	w.countsMu.Lock()
+	defer w.countsMu.Unlock()
+	if w.executionCounts == nil {
+		w.executionCounts = make(map[string]uint64)
+	}
+	w.executionCounts[hex.EncodeToString(code)]++
+}
+
+// GetExecutionCounts returns how many times each code was called since the Wasmer was created or the
+// counts were last reset, keyed by the hex encoded CodeID. All entry points are counted, whether the
+// call succeeded or not, as long as it reached the VM. This helps to find hot contracts.
+func (w *Wasmer) GetExecutionCounts() map[string]uint64 {
+	w.countsMu.Lock()
+	defer w.countsMu.Unlock()
+	res := make(map[string]uint64, len(w.executionCounts))
+	for k, v := range w.executionCounts {
+		res[k] = v
+	}
+	return res
+}
+
+// ResetExecutionCounts clears all execution counts. Call it periodically to keep the counts
+// from growing with every code ever called.
+func (w *Wasmer) ResetExecutionCounts() {
+	w.countsMu.Lock()
+	defer w.countsMu.Unlock()
+	w.executionCounts = nil
+}
+
+// CompileMetrics holds the time spent storing and compiling code in Create
+type CompileMetrics struct {
+	// Count is the number of codes passed to the VM, including the ones that failed to compile
+	Count uint64
+	// Total is the time all of them took together
+	Total time.Duration
+}
+
+// countCompile adds a call of the VM to store and compile code, which began at start
+func (w *Wasmer) countCompile(start time.Time) {
+	elapsed := time.Since(start)
+	w.countsMu.Lock()
+	defer w.countsMu.Unlock()
+	w.compileMetrics.Count++
+	w.compileMetrics.Total += elapsed
+}
+
+// GetCompileMetrics returns the time spent compiling code since the Wasmer was created.
+// This shows whether compilation is a bottleneck, e.g. during genesis import.
+func (w *Wasmer) GetCompileMetrics() CompileMetrics {
+	w.countsMu.Lock()
+	defer w.countsMu.Unlock()
+	return w.compileMetrics
+}
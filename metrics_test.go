@@ -0,0 +1,50 @@
+package cosmwasm
+
+import (
+	"encoding/hex"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/CosmWasm/go-cosmwasm/types"
+)
+
+func TestExecutionCounts(t *testing.T) {
+	wasmer, cleanup := withWasmer(t, types.VMConfig{})
+	defer cleanup()
+	id, _ := createTestCode(t, wasmer, "hackatom.wasm")
+	assert.Empty(t, wasmer.GetExecutionCounts())
+
+	store := newMemStore()
+	msg := []byte(`{"verifier": "fred", "beneficiary": "bob"}`)
+	_, _, err := wasmer.Instantiate(id, mockEnv("creator"), msg, store, mockAPI(), nil, &readOnlyMeter{}, 100000000)
+	require.NoError(t, err)
+	_, _, err = wasmer.Query(id, []byte(`{"verifier":{}}`), store, mockAPI(), nil, &readOnlyMeter{}, 100000000)
+	require.NoError(t, err)
+	// failed calls count as well
+	_, _, err = wasmer.Execute(id, mockEnv("bob"), []byte(`{"release":{}}`), store, mockAPI(), nil, &readOnlyMeter{}, 100000000)
+	require.Error(t, err)
+
+	counts := wasmer.GetExecutionCounts()
+	assert.Equal(t, map[string]uint64{hex.EncodeToString(id): 3}, counts)
+
+	wasmer.ResetExecutionCounts()
+	assert.Empty(t, wasmer.GetExecutionCounts())
+}
+
+func TestCompileMetrics(t *testing.T) {
+	wasmer, cleanup := withWasmer(t, types.VMConfig{})
+	defer cleanup()
+	assert.Equal(t, CompileMetrics{}, wasmer.GetCompileMetrics())
+
+	createTestCode(t, wasmer, "hackatom.wasm")
+	createTestCode(t, wasmer, "queue.wasm")
+	// invalid code is rejected before it reaches the VM
+	_, err := wasmer.Create([]byte("foobar"))
+	require.Error(t, err)
+
+	metrics := wasmer.GetCompileMetrics()
+	assert.Equal(t, uint64(2), metrics.Count)
+	assert.True(t, metrics.Total > 0)
+}
@@ -0,0 +1,95 @@
+package cosmwasm
+
+import (
+	"bytes"
+	"fmt"
+
+	dbm "github.com/tendermint/tm-db"
+
+	"github.com/CosmWasm/go-cosmwasm/types"
+)
+
+// memStore is a KVStore backed by an in-memory db, panicking on errors
+type memStore struct {
+	db *dbm.MemDB
+}
+
+func newMemStore() memStore {
+	return memStore{db: dbm.NewMemDB()}
+}
+
+func (s memStore) Get(key []byte) []byte {
+	v, err := s.db.Get(key)
+	if err != nil {
+		panic(err)
+	}
+	return v
+}
+
+func (s memStore) Set(key, value []byte) {
+	if err := s.db.Set(key, value); err != nil {
+		panic(err)
+	}
+}
+
+func (s memStore) Delete(key []byte) {
+	if err := s.db.Delete(key); err != nil {
+		panic(err)
+	}
+}
+
+func (s memStore) Iterator(start, end []byte) dbm.Iterator {
+	iter, err := s.db.Iterator(start, end)
+	if err != nil {
+		panic(err)
+	}
+	return iter
+}
+
+func (s memStore) ReverseIterator(start, end []byte) dbm.Iterator {
+	iter, err := s.db.ReverseIterator(start, end)
+	if err != nil {
+		panic(err)
+	}
+	return iter
+}
+
+var _ KVStore = memStore{}
+
+const canonicalLength = 32
+
+// mockAPI pads human addresses with zeros to get the canonical form
+func mockAPI() GoAPI {
+	return GoAPI{
+		HumanAddress: func(canon []byte) (string, uint64, error) {
+			if len(canon) != canonicalLength {
+				return "", 0, fmt.Errorf("wrong canonical length")
+			}
+			return string(bytes.TrimRight(canon, "\x00")), 550, nil
+		},
+		CanonicalAddress: func(human string) ([]byte, uint64, error) {
+			if len(human) > canonicalLength {
+				return nil, 0, fmt.Errorf("human encoding too long")
+			}
+			res := make([]byte, canonicalLength)
+			copy(res, human)
+			return res, 440, nil
+		},
+	}
+}
+
+func mockEnv(sender types.HumanAddress) types.Env {
+	return types.Env{
+		Block: types.BlockInfo{
+			Height:  123,
+			Time:    1578939743,
+			ChainID: "foobar",
+		},
+		Message: types.MessageInfo{
+			Sender: sender,
+		},
+		Contract: types.ContractInfo{
+			Address: "contract",
+		},
+	}
+}
@@ -0,0 +1,92 @@
+package cosmwasm
+
+import (
+	"bytes"
+	"encoding/json"
+
+	"github.com/CosmWasm/go-cosmwasm/types"
+)
+
+// marshalMsg encodes a message for a contract. Unlike json.Marshal, it does not escape
+// <, > and & in strings, so the contract sees the strings exactly as they were given.
+func marshalMsg(msg interface{}) ([]byte, error) {
+	var buf bytes.Buffer
+	enc := json.NewEncoder(&buf)
+	enc.SetEscapeHTML(false)
+	if err := enc.Encode(msg); err != nil {
+		return nil, err
+	}
+	// Encode terminates every value with a newline
+	return bytes.TrimSuffix(buf.Bytes(), []byte("\n")), nil
+}
+
+// InstantiateJSON is like Instantiate, but json encodes initMsg for the contract
+func (w *Wasmer) InstantiateJSON(
+	code CodeID,
+	env types.Env,
+	initMsg interface{},
+	store KVStore,
+	goapi GoAPI,
+	querier Querier,
+	gasMeter GasMeter,
+	gasLimit uint64,
+) (*types.InitResponse, uint64, error) {
+	bz, err := marshalMsg(initMsg)
+	if err != nil {
+		return nil, 0, err
+	}
+	return w.Instantiate(code, env, bz, store, goapi, querier, gasMeter, gasLimit)
+}
+
+// ExecuteJSON is like Execute, but json encodes executeMsg for the contract
+func (w *Wasmer) ExecuteJSON(
+	code CodeID,
+	env types.Env,
+	executeMsg interface{},
+	store KVStore,
+	goapi GoAPI,
+	querier Querier,
+	gasMeter GasMeter,
+	gasLimit uint64,
+) (*types.HandleResponse, uint64, error) {
+	bz, err := marshalMsg(executeMsg)
+	if err != nil {
+		return nil, 0, err
+	}
+	return w.Execute(code, env, bz, store, goapi, querier, gasMeter, gasLimit)
+}
+
+// QueryJSON is like Query, but json encodes queryMsg for the contract
+func (w *Wasmer) QueryJSON(
+	code CodeID,
+	queryMsg interface{},
+	store KVStore,
+	goapi GoAPI,
+	querier Querier,
+	gasMeter GasMeter,
+	gasLimit uint64,
+) ([]byte, uint64, error) {
+	bz, err := marshalMsg(queryMsg)
+	if err != nil {
+		return nil, 0, err
+	}
+	return w.Query(code, bz, store, goapi, querier, gasMeter, gasLimit)
+}
+
+// MigrateJSON is like Migrate, but json encodes migrateMsg for the contract
+func (w *Wasmer) MigrateJSON(
+	code CodeID,
+	env types.Env,
+	migrateMsg interface{},
+	store KVStore,
+	goapi GoAPI,
+	querier Querier,
+	gasMeter GasMeter,
+	gasLimit uint64,
+) (*types.MigrateResponse, uint64, error) {
+	bz, err := marshalMsg(migrateMsg)
+	if err != nil {
+		return nil, 0, err
+	}
+	return w.Migrate(code, env, bz, store, goapi, querier, gasMeter, gasLimit)
+}
@@ -0,0 +1,43 @@
+package cosmwasm
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/CosmWasm/go-cosmwasm/types"
+)
+
+func TestMarshalMsg(t *testing.T) {
+	bz, err := marshalMsg(map[string]string{"memo": "<a&b>"})
+	require.NoError(t, err)
+	assert.Equal(t, `{"memo":"<a&b>"}`, string(bz))
+
+	// raw messages are passed through
+	bz, err = marshalMsg(json.RawMessage(`{"release":{}}`))
+	require.NoError(t, err)
+	assert.Equal(t, `{"release":{}}`, string(bz))
+
+	_, err = marshalMsg(make(chan int))
+	assert.Error(t, err)
+}
+
+func TestInstantiateJSON(t *testing.T) {
+	wasmer, cleanup := withWasmer(t, types.VMConfig{})
+	defer cleanup()
+	id, _ := createTestCode(t, wasmer, "hackatom.wasm")
+
+	type initMsg struct {
+		Verifier    string `json:"verifier"`
+		Beneficiary string `json:"beneficiary"`
+	}
+	store := newMemStore()
+	_, _, err := wasmer.InstantiateJSON(id, mockEnv("creator"), initMsg{"fred", "bob"}, store, mockAPI(), nil, &readOnlyMeter{}, 100000000)
+	require.NoError(t, err)
+
+	res, _, err := wasmer.QueryJSON(id, map[string]interface{}{"verifier": struct{}{}}, store, mockAPI(), nil, &readOnlyMeter{}, 100000000)
+	require.NoError(t, err)
+	assert.Equal(t, `{"verifier":"fred"}`, string(res))
+}
@@ -0,0 +1,75 @@
+package cosmwasm
+
+import (
+	"fmt"
+	"sort"
+)
+
+// HostGasProfiler can be implemented by the GasMeter passed to an entry point in order to receive
+// the gas used by every host function call, keyed by the name of the import (e.g. "db_read").
+// Profiling is off for gas meters that do not implement it, so it adds no overhead by default.
+type HostGasProfiler interface {
+	ProfileHostGas(function string, gas uint64)
+}
+
+// ProfilingGasMeter wraps a GasMeter and sums up the gas used by each host function in Profile.
+// Pass it as the gas meter of an entry point to get a breakdown of the gas used externally.
+type ProfilingGasMeter struct {
+	GasMeter
+	Profile map[string]uint64
+}
+
+var _ WasmGasMeter = (*ProfilingGasMeter)(nil)
+var _ HostGasProfiler = (*ProfilingGasMeter)(nil)
+
+// NewProfilingGasMeter wraps the given gas meter with an empty profile
+func NewProfilingGasMeter(meter GasMeter) *ProfilingGasMeter {
+	return &ProfilingGasMeter{
+		GasMeter: meter,
+		Profile:  make(map[string]uint64),
+	}
+}
+
+// ProfileHostGas adds the gas used by one call of function to the profile
+func (m *ProfilingGasMeter) ProfileHostGas(function string, gas uint64) {
+	m.Profile[function] += gas
+}
+
+// ConsumeWasmGas forwards to the wrapped meter if it is a WasmGasMeter
+func (m *ProfilingGasMeter) ConsumeWasmGas(amount uint64, descriptor string) {
+	if wm, ok := m.GasMeter.(WasmGasMeter); ok {
+		wm.ConsumeWasmGas(amount, descriptor)
+	}
+}
+
+// GasDiff is the change in gas of a single host function between two profiles
+type GasDiff struct {
+	Function string
+	Before   uint64
+	After    uint64
+}
+
+func (d GasDiff) String() string {
+	return fmt.Sprintf("%s: %d -> %d (%+d)", d.Function, d.Before, d.After, int64(d.After)-int64(d.Before))
+}
+
+// DiffGasProfiles compares two profiles of the same call, e.g. under two gas configs or against a recorded
+// baseline, and returns the functions whose gas changed, sorted by name. Functions missing in one of the
+// profiles count as 0. Add the total gas used under a key like "total" to include it in the diff.
+func DiffGasProfiles(before, after map[string]uint64) []GasDiff {
+	var res []GasDiff
+	for function, gas := range before {
+		if after[function] != gas {
+			res = append(res, GasDiff{Function: function, Before: gas, After: after[function]})
+		}
+	}
+	for function, gas := range after {
+		if _, ok := before[function]; !ok && gas != 0 {
+			res = append(res, GasDiff{Function: function, After: gas})
+		}
+	}
+	sort.Slice(res, func(i, j int) bool {
+		return res[i].Function < res[j].Function
+	})
+	return res
+}
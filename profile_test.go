@@ -0,0 +1,106 @@
+package cosmwasm
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/CosmWasm/go-cosmwasm/types"
+)
+
+// chargingStore consumes a fixed amount of gas per operation, like the sdk gas kv store
+type chargingStore struct {
+	memStore
+	meter *sdkMeter
+}
+
+func (s chargingStore) Get(key []byte) []byte {
+	s.meter.ConsumeGas(10, "get")
+	return s.memStore.Get(key)
+}
+
+func (s chargingStore) Set(key, value []byte) {
+	s.meter.ConsumeGas(100, "set")
+	s.memStore.Set(key, value)
+}
+
+func (s chargingStore) Delete(key []byte) {
+	s.meter.ConsumeGas(50, "delete")
+	s.memStore.Delete(key)
+}
+
+func TestProfilingGasMeter(t *testing.T) {
+	sdk := &sdkMeter{}
+	meter := NewProfilingGasMeter(NewMultipliedGasMeter(sdk, 1))
+	w := &Wasmer{}
+	store := chargingStore{memStore: newMemStore(), meter: sdk}
+	call := w.newCallContext(store, mockAPI(), nil, meter)
+
+	call.store.Set([]byte("foo"), []byte("bar"))
+	call.store.Get([]byte("foo"))
+	call.store.Get([]byte("foo"))
+	_, _, err := call.api.CanonicalAddress("fred")
+	require.NoError(t, err)
+
+	assert.Equal(t, map[string]uint64{
+		"db_write":             100,
+		"db_read":              20,
+		"canonicalize_address": 440,
+	}, meter.Profile)
+
+	// wasm gas is still charged on the wrapped meter
+	meter.ConsumeWasmGas(5, "wasm")
+	assert.Equal(t, uint64(125), sdk.consumed)
+}
+
+func TestInstantiateProfile(t *testing.T) {
+	wasmer, cleanup := withWasmer(t, types.VMConfig{})
+	defer cleanup()
+	id, _ := createTestCode(t, wasmer, "hackatom.wasm")
+
+	sdk := &sdkMeter{}
+	meter := NewProfilingGasMeter(NewMultipliedGasMeter(sdk, 1))
+	store := chargingStore{memStore: newMemStore(), meter: sdk}
+	msg := []byte(`{"verifier": "fred", "beneficiary": "bob"}`)
+	_, _, err := wasmer.Instantiate(id, mockEnv("creator"), msg, store, mockAPI(), nil, meter, 100000000)
+	require.NoError(t, err)
+
+	assert.Equal(t, uint64(100), meter.Profile["db_write"])
+	assert.Equal(t, uint64(3*440), meter.Profile["canonicalize_address"])
+}
+
+func TestDiffGasProfiles(t *testing.T) {
+	before := map[string]uint64{"db_read": 20, "db_write": 100, "db_remove": 5}
+	after := map[string]uint64{"db_read": 30, "db_write": 100, "db_scan": 7}
+	diff := DiffGasProfiles(before, after)
+	assert.Equal(t, []GasDiff{
+		{Function: "db_read", Before: 20, After: 30},
+		{Function: "db_remove", Before: 5, After: 0},
+		{Function: "db_scan", Before: 0, After: 7},
+	}, diff)
+	assert.Equal(t, "db_remove: 5 -> 0 (-5)", diff[1].String())
+	assert.Empty(t, DiffGasProfiles(before, before))
+}
+
+func TestDiffGasConfigs(t *testing.T) {
+	profile := func(config types.GasConfig) map[string]uint64 {
+		wasmer, cleanup := withWasmer(t, types.VMConfig{GasConfig: config})
+		defer cleanup()
+		id, _ := createTestCode(t, wasmer, "queue.wasm")
+		store := newMemStore()
+		_, _, err := wasmer.Instantiate(id, mockEnv("creator"), []byte(`{}`), store, mockAPI(), nil, &sdkMeter{}, 100000000)
+		require.NoError(t, err)
+		_, _, err = wasmer.Execute(id, mockEnv("creator"), []byte(`{"enqueue":{"value":17}}`), store, mockAPI(), nil, &sdkMeter{}, 100000000)
+		require.NoError(t, err)
+
+		sdk := &sdkMeter{}
+		meter := NewProfilingGasMeter(NewMultipliedGasMeter(sdk, 1))
+		_, _, err = wasmer.Query(id, []byte(`{"sum":{}}`), store, mockAPI(), nil, meter, 100000000)
+		require.NoError(t, err)
+		return meter.Profile
+	}
+
+	diff := DiffGasProfiles(profile(types.GasConfig{}), profile(types.GasConfig{IterCreateCost: 1000}))
+	assert.Equal(t, []GasDiff{{Function: "db_scan", Before: 0, After: 1000}}, diff)
+}
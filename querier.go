@@ -0,0 +1,59 @@
+package cosmwasm
+
+import (
+	"encoding/json"
+
+	"github.com/CosmWasm/go-cosmwasm/types"
+)
+
+// limitedQuerier caps the gas limit of every query at max
+type limitedQuerier struct {
+	Querier
+	max uint64
+}
+
+var _ Querier = limitedQuerier{}
+
+func (q limitedQuerier) Query(request types.QueryRequest, gasLimit uint64) ([]byte, error) {
+	if gasLimit > q.max {
+		gasLimit = q.max
+	}
+	return q.Querier.Query(request, gasLimit)
+}
+
+// totalGasQuerier limits the gas all queries of a single call can use together to max.
+// The gas limit of each query is capped at what is left, and once it is used up all
+// further queries fail with a system error.
+type totalGasQuerier struct {
+	Querier
+	max  uint64
+	used *uint64
+}
+
+var _ Querier = totalGasQuerier{}
+
+func (q totalGasQuerier) Query(request types.QueryRequest, gasLimit uint64) ([]byte, error) {
+	if *q.used >= q.max {
+		bz, _ := json.Marshal(request)
+		return nil, types.InvalidRequest{Err: "total query gas limit of the call exceeded", Request: bz}
+	}
+	if left := q.max - *q.used; gasLimit > left {
+		gasLimit = left
+	}
+	before := q.Querier.GasConsumed()
+	defer func() {
+		*q.used += q.Querier.GasConsumed() - before
+	}()
+	return q.Querier.Query(request, gasLimit)
+}
+
+// wrapQuerier applies the query options of the config to the querier of a call
+func (w *Wasmer) wrapQuerier(querier Querier) Querier {
+	if w.config.MaxQueryGas > 0 {
+		querier = limitedQuerier{Querier: querier, max: w.config.MaxQueryGas}
+	}
+	if w.config.MaxTotalQueryGas > 0 {
+		querier = totalGasQuerier{Querier: querier, max: w.config.MaxTotalQueryGas, used: new(uint64)}
+	}
+	return querier
+}
@@ -0,0 +1,71 @@
+package cosmwasm
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/CosmWasm/go-cosmwasm/types"
+)
+
+// recordingQuerier remembers the gas limit of the last query and consumes cost gas per query
+type recordingQuerier struct {
+	gasLimit uint64
+	cost     uint64
+	consumed uint64
+}
+
+func (q *recordingQuerier) Query(request types.QueryRequest, gasLimit uint64) ([]byte, error) {
+	q.gasLimit = gasLimit
+	q.consumed += q.cost
+	return []byte(`{}`), nil
+}
+
+func (q *recordingQuerier) GasConsumed() uint64 {
+	return q.consumed
+}
+
+func TestMaxQueryGas(t *testing.T) {
+	inner := &recordingQuerier{}
+
+	// without a cap, the querier is used as is
+	w := &Wasmer{}
+	assert.Equal(t, Querier(inner), w.wrapQuerier(inner))
+
+	w = &Wasmer{config: types.VMConfig{MaxQueryGas: 5000}}
+	querier := w.wrapQuerier(inner)
+
+	_, err := querier.Query(types.QueryRequest{}, 100000)
+	assert.NoError(t, err)
+	assert.Equal(t, uint64(5000), inner.gasLimit)
+
+	_, err = querier.Query(types.QueryRequest{}, 1234)
+	assert.NoError(t, err)
+	assert.Equal(t, uint64(1234), inner.gasLimit)
+}
+
+func TestMaxTotalQueryGas(t *testing.T) {
+	inner := &recordingQuerier{cost: 400}
+	w := &Wasmer{config: types.VMConfig{MaxTotalQueryGas: 1000}}
+	querier := w.wrapQuerier(inner)
+
+	_, err := querier.Query(types.QueryRequest{}, 100000)
+	assert.NoError(t, err)
+	assert.Equal(t, uint64(1000), inner.gasLimit)
+	_, err = querier.Query(types.QueryRequest{}, 100000)
+	assert.NoError(t, err)
+	assert.Equal(t, uint64(600), inner.gasLimit)
+	_, err = querier.Query(types.QueryRequest{}, 100)
+	assert.NoError(t, err)
+	assert.Equal(t, uint64(100), inner.gasLimit)
+
+	// the last query used more than was left
+	_, err = querier.Query(types.QueryRequest{}, 100000)
+	assert.IsType(t, types.InvalidRequest{}, err)
+	assert.NotNil(t, types.ToSystemError(err))
+	assert.Equal(t, uint64(1200), inner.consumed)
+
+	// every call starts from zero
+	_, err = w.wrapQuerier(inner).Query(types.QueryRequest{}, 100000)
+	assert.NoError(t, err)
+}
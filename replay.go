@@ -0,0 +1,381 @@
+package cosmwasm
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+
+	dbm "github.com/tendermint/tm-db"
+
+	"github.com/CosmWasm/go-cosmwasm/types"
+)
+
+// HostTrace is the log of all host calls of a contract execution, in the order they were made.
+// It can be serialized as json and replayed with a HostReplayer to reproduce the execution
+// exactly, without the store and querier it originally ran against.
+type HostTrace struct {
+	Records []HostRecord `json:"records"`
+}
+
+// HostRecord is a single host call with its input and output. Which fields are set depends on the function:
+//
+//	db_read, db_write, db_remove: Key and Value
+//	db_scan: Key and Value are start and end, Descending the order, Iterator the id of the new iterator
+//	iterator_valid, iterator_next, iterator_key, iterator_value: Iterator, and Valid or the returned key or value in Value
+//	humanize_address, canonicalize_address: Key is the input, Value the output, Gas the cost, Error the error
+//	query_chain: Key is the json request, Result the querier result, Gas the gas reported by the querier
+//
+// For storage functions Gas is the gas consumed on the gas meter during the call.
+type HostRecord struct {
+	Function   string               `json:"function"`
+	Key        []byte               `json:"key"`
+	Value      []byte               `json:"value"`
+	Iterator   int                  `json:"iterator,omitempty"`
+	Descending bool                 `json:"descending,omitempty"`
+	Valid      bool                 `json:"valid,omitempty"`
+	Result     *types.QuerierResult `json:"result,omitempty"`
+	Gas        uint64               `json:"gas,omitempty"`
+	Error      string               `json:"error,omitempty"`
+}
+
+// HostRecorder records all host calls made through its store, api and querier.
+// Pass Store(), API() and Querier() to a contract call instead of the originals, with the same gas meter.
+type HostRecorder struct {
+	store     KVStore
+	api       GoAPI
+	querier   Querier
+	gasMeter  GasMeter
+	trace     HostTrace
+	iterators int
+}
+
+// NewHostRecorder wraps the store, api and querier of a call. gasMeter must be the gas meter passed to the call.
+func NewHostRecorder(store KVStore, goapi GoAPI, querier Querier, gasMeter GasMeter) *HostRecorder {
+	return &HostRecorder{store: store, api: goapi, querier: querier, gasMeter: gasMeter}
+}
+
+// Trace returns the calls recorded so far
+func (r *HostRecorder) Trace() HostTrace {
+	return HostTrace{Records: append([]HostRecord(nil), r.trace.Records...)}
+}
+
+func (r *HostRecorder) add(rec HostRecord) {
+	r.trace.Records = append(r.trace.Records, rec)
+}
+
+// measure runs fn and returns the gas it consumed on the gas meter
+func (r *HostRecorder) measure(fn func()) uint64 {
+	before := r.gasMeter.GasConsumed()
+	fn()
+	return r.gasMeter.GasConsumed() - before
+}
+
+// Store returns the recording store
+func (r *HostRecorder) Store() KVStore {
+	return recordingHostStore{r}
+}
+
+// API returns the recording api
+func (r *HostRecorder) API() GoAPI {
+	return GoAPI{
+		HumanAddress: func(canon []byte) (string, uint64, error) {
+			human, cost, err := r.api.HumanAddress(canon)
+			r.add(HostRecord{Function: "humanize_address", Key: copyBytes(canon), Value: []byte(human), Gas: cost, Error: errorString(err)})
+			return human, cost, err
+		},
+		CanonicalAddress: func(human string) ([]byte, uint64, error) {
+			canon, cost, err := r.api.CanonicalAddress(human)
+			r.add(HostRecord{Function: "canonicalize_address", Key: []byte(human), Value: copyBytes(canon), Gas: cost, Error: errorString(err)})
+			return canon, cost, err
+		},
+	}
+}
+
+// Querier returns the recording querier
+func (r *HostRecorder) Querier() Querier {
+	return recordingHostQuerier{r}
+}
+
+func errorString(err error) string {
+	if err == nil {
+		return ""
+	}
+	return err.Error()
+}
+
+type recordingHostStore struct {
+	r *HostRecorder
+}
+
+func (s recordingHostStore) Get(key []byte) []byte {
+	var value []byte
+	gas := s.r.measure(func() { value = s.r.store.Get(key) })
+	s.r.add(HostRecord{Function: "db_read", Key: copyBytes(key), Value: copyBytes(value), Gas: gas})
+	return value
+}
+
+func (s recordingHostStore) Set(key, value []byte) {
+	gas := s.r.measure(func() { s.r.store.Set(key, value) })
+	s.r.add(HostRecord{Function: "db_write", Key: copyBytes(key), Value: copyBytes(value), Gas: gas})
+}
+
+func (s recordingHostStore) Delete(key []byte) {
+	gas := s.r.measure(func() { s.r.store.Delete(key) })
+	s.r.add(HostRecord{Function: "db_remove", Key: copyBytes(key), Gas: gas})
+}
+
+func (s recordingHostStore) Iterator(start, end []byte) dbm.Iterator {
+	return s.scan(start, end, false)
+}
+
+func (s recordingHostStore) ReverseIterator(start, end []byte) dbm.Iterator {
+	return s.scan(start, end, true)
+}
+
+func (s recordingHostStore) scan(start, end []byte, descending bool) dbm.Iterator {
+	var it dbm.Iterator
+	gas := s.r.measure(func() {
+		if descending {
+			it = s.r.store.ReverseIterator(start, end)
+		} else {
+			it = s.r.store.Iterator(start, end)
+		}
+	})
+	s.r.iterators++
+	id := s.r.iterators
+	s.r.add(HostRecord{Function: "db_scan", Key: copyBytes(start), Value: copyBytes(end), Descending: descending, Iterator: id, Gas: gas})
+	return recordingHostIterator{Iterator: it, r: s.r, id: id}
+}
+
+type recordingHostIterator struct {
+	dbm.Iterator
+	r  *HostRecorder
+	id int
+}
+
+func (it recordingHostIterator) Valid() bool {
+	var valid bool
+	gas := it.r.measure(func() { valid = it.Iterator.Valid() })
+	it.r.add(HostRecord{Function: "iterator_valid", Iterator: it.id, Valid: valid, Gas: gas})
+	return valid
+}
+
+func (it recordingHostIterator) Next() {
+	gas := it.r.measure(func() { it.Iterator.Next() })
+	it.r.add(HostRecord{Function: "iterator_next", Iterator: it.id, Gas: gas})
+}
+
+func (it recordingHostIterator) Key() []byte {
+	var key []byte
+	gas := it.r.measure(func() { key = it.Iterator.Key() })
+	it.r.add(HostRecord{Function: "iterator_key", Iterator: it.id, Value: copyBytes(key), Gas: gas})
+	return key
+}
+
+func (it recordingHostIterator) Value() []byte {
+	var value []byte
+	gas := it.r.measure(func() { value = it.Iterator.Value() })
+	it.r.add(HostRecord{Function: "iterator_value", Iterator: it.id, Value: copyBytes(value), Gas: gas})
+	return value
+}
+
+type recordingHostQuerier struct {
+	r *HostRecorder
+}
+
+func (q recordingHostQuerier) Query(request types.QueryRequest, gasLimit uint64) ([]byte, error) {
+	bz, err := json.Marshal(request)
+	if err != nil {
+		return nil, err
+	}
+	before := q.r.querier.GasConsumed()
+	res, err := q.r.querier.Query(request, gasLimit)
+	result := types.ToQuerierResult(res, err)
+	q.r.add(HostRecord{Function: "query_chain", Key: bz, Result: &result, Gas: q.r.querier.GasConsumed() - before})
+	return res, err
+}
+
+func (q recordingHostQuerier) GasConsumed() uint64 {
+	return q.r.querier.GasConsumed()
+}
+
+// HostReplayer plays back a HostTrace instead of calling a real store, api and querier.
+// Every call must match the next record of the trace, otherwise the callback panics, which
+// fails the contract call with a HostPanicError. Storage gas is charged on the gas meter
+// as it was recorded.
+type HostReplayer struct {
+	trace     HostTrace
+	pos       int
+	gasMeter  GasMeter
+	queryGas  uint64
+	iterators int
+}
+
+// NewHostReplayer returns a replayer for the trace. gasMeter must be the gas meter passed to the call.
+// If the trace contains storage gas, it must implement WasmGasMeter or SDKGasMeter to be charged with it.
+func NewHostReplayer(trace HostTrace, gasMeter GasMeter) *HostReplayer {
+	return &HostReplayer{trace: trace, gasMeter: gasMeter}
+}
+
+// Done returns an error if not all records of the trace were replayed
+func (r *HostReplayer) Done() error {
+	if r.pos != len(r.trace.Records) {
+		return fmt.Errorf("replay: %d of %d host calls replayed", r.pos, len(r.trace.Records))
+	}
+	return nil
+}
+
+// next returns the next record, which must be a call of function with the given input
+func (r *HostReplayer) next(function string, iterator int, key []byte) HostRecord {
+	if r.pos >= len(r.trace.Records) {
+		panic(fmt.Sprintf("replay: unexpected %s after the end of the trace", function))
+	}
+	rec := r.trace.Records[r.pos]
+	if rec.Function != function || rec.Iterator != iterator || !bytes.Equal(rec.Key, key) {
+		panic(fmt.Sprintf("replay: host call %d: expected %s %d %X, got %s %d %X", r.pos, rec.Function, rec.Iterator, rec.Key, function, iterator, key))
+	}
+	r.pos++
+	return rec
+}
+
+// nextStore is next for storage functions, which charges the recorded gas.
+// Gas that cannot be charged on the gas meter fails the call, as the replay would use less gas than the original.
+func (r *HostReplayer) nextStore(function string, iterator int, key []byte) HostRecord {
+	rec := r.next(function, iterator, key)
+	if rec.Gas > 0 {
+		if !canConsumeGas(r.gasMeter) {
+			panic(fmt.Sprintf("replay: host call %d: cannot charge the recorded gas of %s on a %T", r.pos-1, function, r.gasMeter))
+		}
+		consumeGas(r.gasMeter, rec.Gas, "replayed "+function)
+	}
+	return rec
+}
+
+// Store returns a store replaying the storage calls of the trace
+func (r *HostReplayer) Store() KVStore {
+	return replayHostStore{r}
+}
+
+// API returns an api replaying the address conversions of the trace
+func (r *HostReplayer) API() GoAPI {
+	return GoAPI{
+		HumanAddress: func(canon []byte) (string, uint64, error) {
+			rec := r.next("humanize_address", 0, canon)
+			return string(rec.Value), rec.Gas, replayedError(rec.Error)
+		},
+		CanonicalAddress: func(human string) ([]byte, uint64, error) {
+			rec := r.next("canonicalize_address", 0, []byte(human))
+			return rec.Value, rec.Gas, replayedError(rec.Error)
+		},
+	}
+}
+
+// Querier returns a querier replaying the queries of the trace
+func (r *HostReplayer) Querier() Querier {
+	return replayHostQuerier{r}
+}
+
+func replayedError(msg string) error {
+	if msg == "" {
+		return nil
+	}
+	return errors.New(msg)
+}
+
+type replayHostStore struct {
+	r *HostReplayer
+}
+
+func (s replayHostStore) Get(key []byte) []byte {
+	return s.r.nextStore("db_read", 0, key).Value
+}
+
+func (s replayHostStore) Set(key, value []byte) {
+	rec := s.r.nextStore("db_write", 0, key)
+	if !bytes.Equal(rec.Value, value) {
+		panic(fmt.Sprintf("replay: host call %d: expected db_write of %X, got %X", s.r.pos-1, rec.Value, value))
+	}
+}
+
+func (s replayHostStore) Delete(key []byte) {
+	s.r.nextStore("db_remove", 0, key)
+}
+
+func (s replayHostStore) Iterator(start, end []byte) dbm.Iterator {
+	return s.scan(start, end, false)
+}
+
+func (s replayHostStore) ReverseIterator(start, end []byte) dbm.Iterator {
+	return s.scan(start, end, true)
+}
+
+func (s replayHostStore) scan(start, end []byte, descending bool) dbm.Iterator {
+	s.r.iterators++
+	rec := s.r.nextStore("db_scan", s.r.iterators, start)
+	if !bytes.Equal(rec.Value, end) || rec.Descending != descending {
+		panic(fmt.Sprintf("replay: host call %d: expected db_scan to %X descending %t, got %X %t", s.r.pos-1, rec.Value, rec.Descending, end, descending))
+	}
+	return &replayHostIterator{r: s.r, id: rec.Iterator, start: start, end: end}
+}
+
+type replayHostIterator struct {
+	r          *HostReplayer
+	id         int
+	start, end []byte
+}
+
+var _ dbm.Iterator = (*replayHostIterator)(nil)
+
+func (it *replayHostIterator) Domain() ([]byte, []byte) {
+	return it.start, it.end
+}
+
+func (it *replayHostIterator) Valid() bool {
+	return it.r.nextStore("iterator_valid", it.id, nil).Valid
+}
+
+func (it *replayHostIterator) Next() {
+	it.r.nextStore("iterator_next", it.id, nil)
+}
+
+func (it *replayHostIterator) Key() []byte {
+	return it.r.nextStore("iterator_key", it.id, nil).Value
+}
+
+func (it *replayHostIterator) Value() []byte {
+	return it.r.nextStore("iterator_value", it.id, nil).Value
+}
+
+func (it *replayHostIterator) Error() error {
+	return nil
+}
+
+func (it *replayHostIterator) Close() {}
+
+type replayHostQuerier struct {
+	r *HostReplayer
+}
+
+func (q replayHostQuerier) Query(request types.QueryRequest, _ uint64) ([]byte, error) {
+	bz, err := json.Marshal(request)
+	if err != nil {
+		return nil, err
+	}
+	rec := q.r.next("query_chain", 0, bz)
+	q.r.queryGas += rec.Gas
+	switch {
+	case rec.Result == nil:
+		panic("replay: query_chain without result")
+	case rec.Result.Err != nil:
+		return nil, *rec.Result.Err
+	case rec.Result.Ok.Err != nil:
+		return nil, *rec.Result.Ok.Err
+	default:
+		return rec.Result.Ok.Ok, nil
+	}
+}
+
+func (q replayHostQuerier) GasConsumed() uint64 {
+	return q.r.queryGas
+}
@@ -0,0 +1,72 @@
+package cosmwasm
+
+import (
+	"encoding/json"
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/CosmWasm/go-cosmwasm/types"
+)
+
+func TestRecordAndReplay(t *testing.T) {
+	wasmer, cleanup := withWasmer(t, types.VMConfig{})
+	defer cleanup()
+	id, _ := createTestCode(t, wasmer, "queue.wasm")
+
+	store := newMemStore()
+	_, _, err := wasmer.Instantiate(id, mockEnv("creator"), []byte(`{}`), store, mockAPI(), nil, &readOnlyMeter{}, 100000000)
+	require.NoError(t, err)
+	for _, msg := range []string{`{"enqueue":{"value":17}}`, `{"enqueue":{"value":23}}`} {
+		_, _, err = wasmer.Execute(id, mockEnv("creator"), []byte(msg), store, mockAPI(), nil, &readOnlyMeter{}, 100000000)
+		require.NoError(t, err)
+	}
+
+	// dequeue iterates and removes, the store charges gas for every read and write
+	msg := []byte(`{"dequeue":{}}`)
+	sdk := &sdkMeter{}
+	recorder := NewHostRecorder(chargingStore{memStore: store, meter: sdk}, mockAPI(), nil, sdk)
+	res, gasUsed, err := wasmer.Execute(id, mockEnv("creator"), msg, recorder.Store(), recorder.API(), recorder.Querier(), sdk, 100000000)
+	require.NoError(t, err)
+	trace := recorder.Trace()
+	require.NotEmpty(t, trace.Records)
+	assert.Equal(t, "db_scan", trace.Records[0].Function)
+	var storeGas uint64
+	for _, rec := range trace.Records {
+		storeGas += rec.Gas
+	}
+	assert.Equal(t, sdk.consumed, storeGas)
+	require.NotZero(t, storeGas)
+
+	bz, err := json.Marshal(trace)
+	require.NoError(t, err)
+	var decoded HostTrace
+	require.NoError(t, json.Unmarshal(bz, &decoded))
+
+	// the replay does not need the state, and charges the same gas
+	replaySDK := &sdkMeter{}
+	replayer := NewHostReplayer(decoded, replaySDK)
+	replayed, replayedGas, err := wasmer.Execute(id, mockEnv("creator"), msg, replayer.Store(), replayer.API(), replayer.Querier(), replaySDK, 100000000)
+	require.NoError(t, err)
+	assert.Equal(t, res, replayed)
+	assert.Equal(t, gasUsed, replayedGas)
+	assert.Equal(t, sdk.consumed, replaySDK.consumed)
+	assert.NoError(t, replayer.Done())
+
+	// the recorded gas cannot be charged on a meter without ConsumeGas
+	meter := &readOnlyMeter{}
+	replayer = NewHostReplayer(decoded, meter)
+	_, _, err = wasmer.Execute(id, mockEnv("creator"), msg, replayer.Store(), replayer.API(), replayer.Querier(), meter, 100000000)
+	var panicErr types.HostPanicError
+	require.True(t, errors.As(err, &panicErr))
+	assert.Contains(t, panicErr.Error(), "cannot charge the recorded gas")
+
+	// diverging from the trace fails the call
+	replayer = NewHostReplayer(decoded, replaySDK)
+	_, _, err = wasmer.Execute(id, mockEnv("creator"), []byte(`{"enqueue":{"value":5}}`), replayer.Store(), replayer.API(), replayer.Querier(), replaySDK, 100000000)
+	require.True(t, errors.As(err, &panicErr))
+	assert.Contains(t, panicErr.Error(), "replay: host call 0")
+	assert.Error(t, replayer.Done())
+}
@@ -0,0 +1,23 @@
+package cosmwasm
+
+import (
+	"github.com/CosmWasm/go-cosmwasm/types"
+)
+
+// SimulateExecute runs execute like Execute, but against a cache of store whose writes are discarded,
+// so store is never modified. It returns the gas the call used and the error it would fail with,
+// or nil if it would succeed. Gas is charged on gasMeter as usual, so pass a throwaway meter if the
+// simulation must not be charged, e.g. for fee estimation or mempool filtering.
+func (w *Wasmer) SimulateExecute(
+	code CodeID,
+	env types.Env,
+	executeMsg []byte,
+	store KVStore,
+	goapi GoAPI,
+	querier Querier,
+	gasMeter GasMeter,
+	gasLimit uint64,
+) (uint64, error) {
+	_, gasUsed, err := w.Execute(code, env, executeMsg, NewCacheKVStore(store), goapi, querier, gasMeter, gasLimit)
+	return gasUsed, err
+}
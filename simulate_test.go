@@ -0,0 +1,39 @@
+package cosmwasm
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/CosmWasm/go-cosmwasm/types"
+)
+
+func TestSimulateExecute(t *testing.T) {
+	wasmer, cleanup := withWasmer(t, types.VMConfig{})
+	defer cleanup()
+	id, _ := createTestCode(t, wasmer, "queue.wasm")
+
+	store := newMemStore()
+	_, _, err := wasmer.Instantiate(id, mockEnv("creator"), []byte(`{}`), store, mockAPI(), nil, &readOnlyMeter{}, 100000000)
+	require.NoError(t, err)
+	count := func() string {
+		res, _, err := wasmer.Query(id, []byte(`{"count":{}}`), store, mockAPI(), nil, &readOnlyMeter{}, 100000000)
+		require.NoError(t, err)
+		return string(res)
+	}
+
+	msg := []byte(`{"enqueue":{"value":17}}`)
+	gasUsed, err := wasmer.SimulateExecute(id, mockEnv("creator"), msg, store, mockAPI(), nil, &readOnlyMeter{}, 100000000)
+	require.NoError(t, err)
+	assert.Equal(t, `{"count":0}`, count())
+
+	// the simulation uses as much gas as the real call
+	_, realGas, err := wasmer.Execute(id, mockEnv("creator"), msg, store, mockAPI(), nil, &readOnlyMeter{}, 100000000)
+	require.NoError(t, err)
+	assert.Equal(t, realGas, gasUsed)
+	assert.Equal(t, `{"count":1}`, count())
+
+	_, err = wasmer.SimulateExecute(id, mockEnv("creator"), msg, store, mockAPI(), nil, &readOnlyMeter{}, 1000)
+	assert.Error(t, err)
+}
@@ -0,0 +1,155 @@
+// Package snapshot runs contracts with a fixed env and encodes the results in a stable form,
+// so they can be compared against golden files in integration tests.
+package snapshot
+
+import (
+	"bytes"
+	"encoding/json"
+	"sort"
+
+	cosmwasm "github.com/CosmWasm/go-cosmwasm"
+	"github.com/CosmWasm/go-cosmwasm/types"
+)
+
+// Env returns an env that is the same in every run, with the given sender and no funds
+func Env(sender types.HumanAddress) types.Env {
+	return types.Env{
+		Block: types.BlockInfo{
+			Height:  12345,
+			Time:    1571797419,
+			ChainID: "snapshot",
+		},
+		Message: types.MessageInfo{
+			Sender:    sender,
+			SentFunds: types.Coins{},
+		},
+		Contract: types.ContractInfo{
+			Address: "contract",
+		},
+	}
+}
+
+// Result is the outcome of a contract call as it is written to a snapshot.
+// Exactly one of Response and Error is set.
+type Result struct {
+	Response interface{} `json:"response,omitempty"`
+	Error    string      `json:"error,omitempty"`
+	GasUsed  uint64      `json:"gas_used"`
+}
+
+// Instantiate runs instantiate with Env(sender) and returns the snapshot of the result.
+// Errors of the call are part of the snapshot, so only encoding can fail.
+func Instantiate(vm cosmwasm.VM, code cosmwasm.CodeID, sender types.HumanAddress, msg []byte, store cosmwasm.KVStore,
+	goapi cosmwasm.GoAPI, querier cosmwasm.Querier, gasMeter cosmwasm.GasMeter, gasLimit uint64) ([]byte, error) {
+	res, gasUsed, err := vm.Instantiate(code, Env(sender), msg, store, goapi, querier, gasMeter, gasLimit)
+	if err != nil {
+		return Marshal(Result{Error: err.Error(), GasUsed: gasUsed})
+	}
+	return Marshal(Result{Response: res, GasUsed: gasUsed})
+}
+
+// Execute runs execute with Env(sender) and returns the snapshot of the result
+func Execute(vm cosmwasm.VM, code cosmwasm.CodeID, sender types.HumanAddress, msg []byte, store cosmwasm.KVStore,
+	goapi cosmwasm.GoAPI, querier cosmwasm.Querier, gasMeter cosmwasm.GasMeter, gasLimit uint64) ([]byte, error) {
+	res, gasUsed, err := vm.Execute(code, Env(sender), msg, store, goapi, querier, gasMeter, gasLimit)
+	if err != nil {
+		return Marshal(Result{Error: err.Error(), GasUsed: gasUsed})
+	}
+	return Marshal(Result{Response: res, GasUsed: gasUsed})
+}
+
+// Query runs a query and returns the snapshot of the result. A json result is embedded as json.
+func Query(vm cosmwasm.VM, code cosmwasm.CodeID, msg []byte, store cosmwasm.KVStore,
+	goapi cosmwasm.GoAPI, querier cosmwasm.Querier, gasMeter cosmwasm.GasMeter, gasLimit uint64) ([]byte, error) {
+	res, gasUsed, err := vm.Query(code, msg, store, goapi, querier, gasMeter, gasLimit)
+	if err != nil {
+		return Marshal(Result{Error: err.Error(), GasUsed: gasUsed})
+	}
+	var response interface{} = res
+	if json.Valid(res) {
+		response = json.RawMessage(res)
+	}
+	return Marshal(Result{Response: response, GasUsed: gasUsed})
+}
+
+// Marshal encodes v as indented json with sorted object keys and a trailing newline.
+// The log and event attributes of contract responses are sorted by key and value first,
+// so the output does not depend on the order a contract emits them in.
+func Marshal(v interface{}) ([]byte, error) {
+	if res, ok := v.(Result); ok {
+		res.Response = normalize(res.Response)
+		v = res
+	} else {
+		v = normalize(v)
+	}
+	bz, err := json.Marshal(v)
+	if err != nil {
+		return nil, err
+	}
+	dec := json.NewDecoder(bytes.NewReader(bz))
+	dec.UseNumber()
+	var doc interface{}
+	if err := dec.Decode(&doc); err != nil {
+		return nil, err
+	}
+	// maps are always encoded with sorted keys
+	out, err := json.MarshalIndent(doc, "", "  ")
+	if err != nil {
+		return nil, err
+	}
+	return append(out, '\n'), nil
+}
+
+// normalize returns a copy of contract responses with sorted attributes, and anything else as it is
+func normalize(v interface{}) interface{} {
+	switch r := v.(type) {
+	case *types.InitResponse:
+		if r == nil {
+			return r
+		}
+		res := *r
+		res.Log, res.Events = sortAttributes(res.Log), sortEvents(res.Events)
+		return &res
+	case *types.HandleResponse:
+		if r == nil {
+			return r
+		}
+		res := *r
+		res.Log, res.Events = sortAttributes(res.Log), sortEvents(res.Events)
+		return &res
+	case *types.MigrateResponse:
+		if r == nil {
+			return r
+		}
+		res := *r
+		res.Log, res.Events = sortAttributes(res.Log), sortEvents(res.Events)
+		return &res
+	}
+	return v
+}
+
+func sortAttributes(attrs []types.LogAttribute) []types.LogAttribute {
+	if attrs == nil {
+		return nil
+	}
+	res := append([]types.LogAttribute{}, attrs...)
+	sort.SliceStable(res, func(i, j int) bool {
+		if res[i].Key != res[j].Key {
+			return res[i].Key < res[j].Key
+		}
+		return res[i].Value < res[j].Value
+	})
+	return res
+}
+
+// sortEvents sorts the attributes of every event, but keeps the events in order
+func sortEvents(events []types.Event) []types.Event {
+	if events == nil {
+		return nil
+	}
+	res := make([]types.Event, len(events))
+	for i, e := range events {
+		res[i] = types.Event{Type: e.Type, Attributes: sortAttributes(e.Attributes)}
+	}
+	return res
+}
@@ -0,0 +1,165 @@
+package snapshot
+
+import (
+	"bytes"
+	"encoding/json"
+	"io/ioutil"
+	"os"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	dbm "github.com/tendermint/tm-db"
+
+	cosmwasm "github.com/CosmWasm/go-cosmwasm"
+	"github.com/CosmWasm/go-cosmwasm/types"
+)
+
+// mapStore is a minimal KVStore for the tests, which do not iterate
+type mapStore map[string][]byte
+
+func (s mapStore) Get(key []byte) []byte {
+	return s[string(key)]
+}
+
+func (s mapStore) Set(key, value []byte) {
+	s[string(key)] = value
+}
+
+func (s mapStore) Delete(key []byte) {
+	delete(s, string(key))
+}
+
+func (s mapStore) Iterator(_, _ []byte) dbm.Iterator {
+	panic("not supported")
+}
+
+func (s mapStore) ReverseIterator(_, _ []byte) dbm.Iterator {
+	panic("not supported")
+}
+
+type gasMeter struct{}
+
+func (gasMeter) GasConsumed() uint64 {
+	return 0
+}
+
+// testAPI pads human addresses with zeros to get the canonical form
+func testAPI() cosmwasm.GoAPI {
+	return cosmwasm.GoAPI{
+		HumanAddress: func(canon []byte) (string, uint64, error) {
+			return string(bytes.TrimRight(canon, "\x00")), 0, nil
+		},
+		CanonicalAddress: func(human string) ([]byte, uint64, error) {
+			res := make([]byte, 32)
+			copy(res, human)
+			return res, 0, nil
+		},
+	}
+}
+
+func TestExecute(t *testing.T) {
+	tmpdir, err := ioutil.TempDir("", "go-cosmwasm")
+	require.NoError(t, err)
+	defer os.RemoveAll(tmpdir)
+	wasmer, err := cosmwasm.NewWasmer(tmpdir, "staking", 0)
+	require.NoError(t, err)
+	defer wasmer.Cleanup()
+	wasm, err := ioutil.ReadFile("../api/testdata/hackatom.wasm")
+	require.NoError(t, err)
+	code, err := wasmer.Create(wasm)
+	require.NoError(t, err)
+
+	run := func() ([]byte, []byte) {
+		store := mapStore{}
+		init, err := Instantiate(wasmer, code, "creator", []byte(`{"verifier": "fred", "beneficiary": "bob"}`), store, testAPI(), nil, gasMeter{}, 100000000)
+		require.NoError(t, err)
+		// only the verifier may release
+		res, err := Execute(wasmer, code, "mallory", []byte(`{"release":{}}`), store, testAPI(), nil, gasMeter{}, 100000000)
+		require.NoError(t, err)
+		return init, res
+	}
+	init, res := run()
+	init2, res2 := run()
+	assert.Equal(t, init, init2)
+	assert.Equal(t, res, res2)
+	// the gas used depends on the VM, so only the response is compared
+	var snapshot struct {
+		GasUsed  uint64          `json:"gas_used"`
+		Response json.RawMessage `json:"response"`
+	}
+	require.NoError(t, json.Unmarshal(init, &snapshot))
+	assert.NotZero(t, snapshot.GasUsed)
+	expected := `{
+    "data": null,
+    "log": [
+      {
+        "key": "Let the",
+        "value": "hacking begin"
+      }
+    ],
+    "messages": []
+  }`
+	assert.Equal(t, expected, string(snapshot.Response))
+	assert.Contains(t, string(res), `"error": "unauthorized"`)
+
+	res, err = Query(wasmer, code, []byte(`{"verifier":{}}`), mapStore{}, testAPI(), nil, gasMeter{}, 100000000)
+	require.NoError(t, err)
+	assert.Contains(t, string(res), `"error": `)
+}
+
+func TestMarshal(t *testing.T) {
+	res := &types.HandleResponse{
+		Log: []types.LogAttribute{{Key: "b", Value: "1"}, {Key: "a", Value: "2"}, {Key: "a", Value: "1"}},
+		Events: []types.Event{
+			{Type: "z", Attributes: []types.LogAttribute{{Key: "y", Value: ""}, {Key: "x", Value: ""}}},
+			{Type: "a"},
+		},
+	}
+	bz, err := Marshal(Result{Response: res, GasUsed: 7})
+	require.NoError(t, err)
+	expected := `{
+  "gas_used": 7,
+  "response": {
+    "data": null,
+    "events": [
+      {
+        "attributes": [
+          {
+            "key": "x",
+            "value": ""
+          },
+          {
+            "key": "y",
+            "value": ""
+          }
+        ],
+        "type": "z"
+      },
+      {
+        "attributes": null,
+        "type": "a"
+      }
+    ],
+    "log": [
+      {
+        "key": "a",
+        "value": "1"
+      },
+      {
+        "key": "a",
+        "value": "2"
+      },
+      {
+        "key": "b",
+        "value": "1"
+      }
+    ],
+    "messages": null
+  }
+}
+`
+	assert.Equal(t, expected, string(bz))
+	// the response itself is not modified
+	assert.Equal(t, "b", res.Log[0].Key)
+}
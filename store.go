@@ -0,0 +1,161 @@
+package cosmwasm
+
+import (
+	"bytes"
+	"fmt"
+
+	dbm "github.com/tendermint/tm-db"
+
+	"github.com/CosmWasm/go-cosmwasm/types"
+)
+
+// ReadOnlyKVStore wraps a KVStore and panics on any write to it.
+// Queries run on a ReadOnlyKVStore, in addition to the callbacks rejecting writes in queries.
+type ReadOnlyKVStore struct {
+	KVStore
+}
+
+var _ KVStore = ReadOnlyKVStore{}
+
+// Set always panics
+func (s ReadOnlyKVStore) Set(key, value []byte) {
+	panic("write to read-only store")
+}
+
+// Delete always panics
+func (s ReadOnlyKVStore) Delete(key []byte) {
+	panic("delete in read-only store")
+}
+
+// EstimateCount forwards to the wrapped store, see IteratorCountEstimator
+func (s ReadOnlyKVStore) EstimateCount(start, end []byte) (uint64, bool) {
+	return estimateCount(s.KVStore, start, end)
+}
+
+// prefixGuardStore panics with ErrKeyNotAllowed on any access to a key without one of the prefixes.
+// The panic fails the contract call with a HostPanicError wrapping the error.
+type prefixGuardStore struct {
+	KVStore
+	prefixes [][]byte
+}
+
+func (s prefixGuardStore) checkKey(key []byte) {
+	for _, p := range s.prefixes {
+		if bytes.HasPrefix(key, p) {
+			return
+		}
+	}
+	panic(fmt.Errorf("%w: %X", types.ErrKeyNotAllowed, key))
+}
+
+// checkRange ensures [start, end) lies within one of the prefixes. nil start or end are unbounded.
+func (s prefixGuardStore) checkRange(start, end []byte) {
+	for _, p := range s.prefixes {
+		if len(p) == 0 {
+			return
+		}
+		if start == nil || !bytes.HasPrefix(start, p) {
+			continue
+		}
+		if limit := prefixEnd(p); limit == nil || (end != nil && bytes.Compare(end, limit) <= 0) {
+			return
+		}
+	}
+	panic(fmt.Errorf("%w: range %X - %X", types.ErrKeyNotAllowed, start, end))
+}
+
+// prefixEnd returns the smallest key larger than all keys with the prefix, or nil if there is none
+func prefixEnd(prefix []byte) []byte {
+	end := copyBytes(prefix)
+	for i := len(end) - 1; i >= 0; i-- {
+		if end[i] < 0xff {
+			end[i]++
+			return end[:i+1]
+		}
+	}
+	return nil
+}
+
+func (s prefixGuardStore) Get(key []byte) []byte {
+	s.checkKey(key)
+	return s.KVStore.Get(key)
+}
+
+func (s prefixGuardStore) Set(key, value []byte) {
+	s.checkKey(key)
+	s.KVStore.Set(key, value)
+}
+
+func (s prefixGuardStore) Delete(key []byte) {
+	s.checkKey(key)
+	s.KVStore.Delete(key)
+}
+
+func (s prefixGuardStore) Iterator(start, end []byte) dbm.Iterator {
+	s.checkRange(start, end)
+	return s.KVStore.Iterator(start, end)
+}
+
+func (s prefixGuardStore) ReverseIterator(start, end []byte) dbm.Iterator {
+	s.checkRange(start, end)
+	return s.KVStore.ReverseIterator(start, end)
+}
+
+func (s prefixGuardStore) EstimateCount(start, end []byte) (uint64, bool) {
+	return estimateCount(s.KVStore, start, end)
+}
+
+// StoreWrite is a single write to a KVStore. Value is nil for deletes.
+type StoreWrite struct {
+	Key    []byte
+	Value  []byte
+	Delete bool
+}
+
+// RecordingKVStore wraps a KVStore and records all writes made through it in order.
+// Wrap the store passed to an entry point with it to see exactly what a contract wrote.
+type RecordingKVStore struct {
+	KVStore
+	writes []StoreWrite
+}
+
+var _ KVStore = (*RecordingKVStore)(nil)
+
+// NewRecordingKVStore wraps the given store
+func NewRecordingKVStore(store KVStore) *RecordingKVStore {
+	return &RecordingKVStore{KVStore: store}
+}
+
+// Set records the write and forwards it to the wrapped store
+func (s *RecordingKVStore) Set(key, value []byte) {
+	s.KVStore.Set(key, value)
+	s.writes = append(s.writes, StoreWrite{Key: copyBytes(key), Value: copyBytes(value)})
+}
+
+// Delete records the delete and forwards it to the wrapped store
+func (s *RecordingKVStore) Delete(key []byte) {
+	s.KVStore.Delete(key)
+	s.writes = append(s.writes, StoreWrite{Key: copyBytes(key), Delete: true})
+}
+
+// EstimateCount forwards to the wrapped store, see IteratorCountEstimator
+func (s *RecordingKVStore) EstimateCount(start, end []byte) (uint64, bool) {
+	return estimateCount(s.KVStore, start, end)
+}
+
+// Writes returns all writes recorded so far
+func (s *RecordingKVStore) Writes() []StoreWrite {
+	return s.writes
+}
+
+// Reset clears the recorded writes
+func (s *RecordingKVStore) Reset() {
+	s.writes = nil
+}
+
+func copyBytes(bz []byte) []byte {
+	if bz == nil {
+		return nil
+	}
+	return append([]byte{}, bz...)
+}
@@ -0,0 +1,91 @@
+package cosmwasm
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/CosmWasm/go-cosmwasm/types"
+)
+
+func TestReadOnlyKVStore(t *testing.T) {
+	mem := newMemStore()
+	mem.Set([]byte("foo"), []byte("bar"))
+	store := ReadOnlyKVStore{mem}
+
+	assert.Equal(t, []byte("bar"), store.Get([]byte("foo")))
+	iter := store.Iterator(nil, nil)
+	assert.True(t, iter.Valid())
+	iter.Close()
+
+	assert.Panics(t, func() { store.Set([]byte("foo"), []byte("baz")) })
+	assert.Panics(t, func() { store.Delete([]byte("foo")) })
+	assert.Equal(t, []byte("bar"), mem.Get([]byte("foo")))
+}
+
+func TestRecordingKVStore(t *testing.T) {
+	wasmer, cleanup := withWasmer(t, types.VMConfig{})
+	defer cleanup()
+	id, _ := createTestCode(t, wasmer, "hackatom.wasm")
+
+	store := NewRecordingKVStore(newMemStore())
+	msg := []byte(`{"verifier": "fred", "beneficiary": "bob"}`)
+	_, _, err := wasmer.Instantiate(id, mockEnv("creator"), msg, store, mockAPI(), nil, &readOnlyMeter{}, 100000000)
+	require.NoError(t, err)
+
+	writes := store.Writes()
+	require.Len(t, writes, 1)
+	assert.Equal(t, []byte("config"), writes[0].Key)
+	assert.False(t, writes[0].Delete)
+	assert.Equal(t, writes[0].Value, store.Get([]byte("config")))
+
+	store.Delete([]byte("config"))
+	assert.Equal(t, StoreWrite{Key: []byte("config"), Delete: true}, store.Writes()[1])
+
+	store.Reset()
+	assert.Empty(t, store.Writes())
+}
+
+func TestPrefixGuardStore(t *testing.T) {
+	store := prefixGuardStore{KVStore: newMemStore(), prefixes: [][]byte{[]byte("a/"), {0x01, 0xff}, {0xff, 0xff}}}
+
+	store.Set([]byte("a/foo"), []byte("bar"))
+	assert.Equal(t, []byte("bar"), store.Get([]byte("a/foo")))
+	assert.Panics(t, func() { store.Get([]byte("b/foo")) })
+	assert.Panics(t, func() { store.Set([]byte("a"), []byte("bar")) })
+	assert.Panics(t, func() { store.Delete([]byte("b/foo")) })
+
+	store.Iterator([]byte("a/"), []byte("a0")).Close()
+	store.ReverseIterator([]byte("a/foo"), []byte("a/goo")).Close()
+	store.Iterator([]byte{0x01, 0xff}, []byte{0x02}).Close()
+	store.Iterator([]byte{0xff, 0xff, 0x01}, nil).Close()
+	store.ReverseIterator([]byte{0xff, 0xff}, nil).Close()
+	assert.Panics(t, func() { store.Iterator(nil, nil) })
+	assert.Panics(t, func() { store.Iterator([]byte("a/"), nil) })
+	assert.Panics(t, func() { store.Iterator([]byte{0x01, 0xff}, nil) })
+	assert.Panics(t, func() { store.Iterator([]byte{0xff}, nil) })
+	assert.Panics(t, func() { store.Iterator([]byte("a/"), []byte("a1")) })
+	assert.Panics(t, func() { store.ReverseIterator([]byte("a"), []byte("a0")) })
+
+	assert.Equal(t, []byte("a0"), prefixEnd([]byte("a/")))
+	assert.Equal(t, []byte{0x02}, prefixEnd([]byte{0x01, 0xff}))
+	assert.Nil(t, prefixEnd([]byte{0xff, 0xff}))
+}
+
+func TestAllowedKeyPrefixes(t *testing.T) {
+	wasmer, cleanup := withWasmer(t, types.VMConfig{AllowedKeyPrefixes: [][]byte{[]byte("other")}})
+	defer cleanup()
+	id, _ := createTestCode(t, wasmer, "hackatom.wasm")
+
+	// hackatom stores its state at "config"
+	msg := []byte(`{"verifier": "fred", "beneficiary": "bob"}`)
+	_, _, err := wasmer.Instantiate(id, mockEnv("creator"), msg, newMemStore(), mockAPI(), nil, &readOnlyMeter{}, 100000000)
+	require.Error(t, err)
+	assert.True(t, errors.Is(err, types.ErrKeyNotAllowed))
+
+	wasmer.config.AllowedKeyPrefixes = [][]byte{[]byte("other"), []byte("conf")}
+	_, _, err = wasmer.Instantiate(id, mockEnv("creator"), msg, newMemStore(), mockAPI(), nil, &readOnlyMeter{}, 100000000)
+	assert.NoError(t, err)
+}
@@ -0,0 +1,33 @@
+package types
+
+// AnalysisReport is the result of the static analysis of a contract's wasm code
+type AnalysisReport struct {
+	// HasIBCEntryPoints is true if the contract exports all of the ibc_* entry points
+	HasIBCEntryPoints bool
+	// HasMigrateEntryPoint is true if the contract exports migrate, which is optional
+	HasMigrateEntryPoint bool
+	// RequiredFeatures is a comma separated list of the features the contract requires
+	// via its requires_* exports or custom sections, in the same format as the supported features of the VM
+	RequiredFeatures string
+	// RequiredFeaturesSet holds the same features as RequiredFeatures, sorted and without duplicates.
+	// It is nil if the contract requires no features.
+	RequiredFeaturesSet []string
+	// InterfaceVersion is the version of the VM interface the contract was built for, as declared by
+	// its cosmwasm_vm_version_N (or interface_version_N) marker export. It is 0 if there is no marker.
+	InterfaceVersion uint32
+}
+
+// CodeInfo contains metadata about a stored code
+type CodeInfo struct {
+	AnalysisReport
+	// Size is the length of the original wasm code in bytes
+	Size uint64
+}
+
+// FunctionSignature describes a function exported by (or imported into) a contract.
+// Value types use the names of the wasm text format, e.g. "i32".
+type FunctionSignature struct {
+	Name    string
+	Params  []string
+	Results []string
+}
@@ -0,0 +1,82 @@
+package types
+
+// DefaultMaxCodeSize is the MaxCodeSize used if it is not set
+const DefaultMaxCodeSize = 1024 * 1024
+
+// VMConfig contains all the configuration of a Wasmer
+type VMConfig struct {
+	// DataDir is the directory where the raw wasm and the pre-compile cache are stored
+	DataDir string
+	// SupportedFeatures is a comma separated list of features the chain supports, e.g. "staking"
+	SupportedFeatures string
+	// CacheSize sets the size of the in-memory LRU cache for prepared VMs
+	CacheSize uint64
+
+	// EnableHealthCheck allows Wasmer.HealthCheck, which stores a tiny probe contract permanently
+	// in DataDir the first time it runs. It is off by default.
+	EnableHealthCheck bool
+
+	// CanonicalEnv encodes the env passed to contracts as canonical json (sorted keys, no whitespace),
+	// so it is byte-identical no matter how it was constructed. It is off by default.
+	CanonicalEnv bool
+
+	// MaxCodeSize is the maximum size in bytes of the code CreateFromReader reads. Larger code is rejected
+	// with ErrCodeTooLarge without reading it all. 0 means DefaultMaxCodeSize.
+	MaxCodeSize int
+
+	// MaxInitialMemoryPages is the largest initial memory size in pages of 64 KiB a contract may declare.
+	// Larger contracts are rejected when they are stored. 0 means no limit besides the one of the VM.
+	MaxInitialMemoryPages uint32
+
+	// MaxJSONDepth is the maximum nesting depth of the json inputs (env, msg) of an entry point.
+	// 0 means unlimited.
+	MaxJSONDepth int
+	// MaxJSONSize is the maximum size in bytes of the json inputs (env, msg) of an entry point.
+	// 0 means unlimited.
+	MaxJSONSize int
+	// MaxMessageSize is the maximum size in bytes of the msg and of the serialized env passed to an entry point.
+	// Larger inputs are rejected before any other work is done. 0 means unlimited.
+	MaxMessageSize int
+	// InputGasPerByte is the wasm gas charged per byte of input for validating the inputs
+	// of an entry point on the host. It is deducted from the gas limit before the contract runs.
+	InputGasPerByte uint64
+	// MaxQueryGas caps the gas limit of a single query a contract makes to the chain.
+	// The VM passes the remaining gas of the call as the limit, so every query gets
+	// min(remaining gas, MaxQueryGas). The gas the query used is deducted from the call as usual.
+	// 0 means no cap.
+	MaxQueryGas uint64
+	// MaxTotalQueryGas caps the gas all queries made during a single contract call can use together.
+	// Once it is used up, further queries fail with a system error. 0 means no cap.
+	MaxTotalQueryGas uint64
+	// ValidateQueryResults checks that non-empty query results of contracts are valid json,
+	// so a buggy contract is caught right away rather than when the result is decoded.
+	// It is off by default as it parses every result once more.
+	ValidateQueryResults bool
+	// MaxResultSize is the maximum size in bytes of the serialized result of a contract call.
+	// Larger results fail the call before they are decoded. 0 means unlimited.
+	MaxResultSize int
+	// MaxMessages is the maximum number of messages a contract may return from instantiate,
+	// execute or migrate. Responses with more messages fail the call. 0 means unlimited.
+	MaxMessages int
+	// AllowedKeyPrefixes restricts the keys a contract may read and write to the ones starting with one
+	// of these prefixes, e.g. the namespace of the contract in a shared store. Iterators must stay within
+	// a single prefix. Other accesses fail the call with ErrKeyNotAllowed. Empty means unrestricted.
+	AllowedKeyPrefixes [][]byte
+	// GasConfig sets the gas the host charges for storage access on top of the store itself.
+	// With any cost set, the gas meter passed to the entry points must implement WasmGasMeter or
+	// SDKGasMeter, otherwise the calls fail with ErrGasMeterNotChargeable.
+	GasConfig GasConfig
+}
+
+// GasConfig holds the gas costs charged by the host for storage iteration. Costs are given in the units
+// of the GasMeter passed to the entry points (wasm gas for a MultipliedGasMeter). Zero values charge nothing.
+type GasConfig struct {
+	// IterCreateCost is charged every time a contract opens an iterator (db_scan)
+	IterCreateCost uint64
+	// IterNextCostPerByte is charged per byte of key and value an iterator returns (db_next)
+	IterNextCostPerByte uint64
+	// IterCostPerEstimatedKey is charged per key when opening an iterator on a store that can estimate
+	// the number of keys in the range (see IteratorCountEstimator). Such iterators are not charged
+	// IterNextCostPerByte. Stores without an estimate are charged per byte as usual.
+	IterCostPerEstimatedKey uint64
+}
@@ -1,5 +1,9 @@
 package types
 
+import (
+	"fmt"
+)
+
 //---------- Env ---------
 
 // Env defines the state of the blockchain environment this contract is
@@ -32,3 +36,56 @@ type ContractInfo struct {
 	// binary encoding of sdk.AccAddress of the contract, to be used when sending messages
 	Address HumanAddress `json:"address"`
 }
+
+// EnvBuilder constructs an Env and checks all required fields are set.
+//
+//	env, err := NewEnvBuilder().
+//		WithBlock(123, 1578939743, "testing").
+//		WithMessage("sender", nil).
+//		WithContract("contract").
+//		Build()
+type EnvBuilder struct {
+	env Env
+}
+
+// NewEnvBuilder returns a builder for an empty Env
+func NewEnvBuilder() *EnvBuilder {
+	return &EnvBuilder{}
+}
+
+// WithBlock sets the block the contract is executed in
+func (b *EnvBuilder) WithBlock(height uint64, time uint64, chainID string) *EnvBuilder {
+	b.env.Block = BlockInfo{Height: height, Time: time, ChainID: chainID}
+	return b
+}
+
+// WithMessage sets the sender of the message and the funds sent along with it
+func (b *EnvBuilder) WithMessage(sender HumanAddress, sentFunds Coins) *EnvBuilder {
+	b.env.Message = MessageInfo{Sender: sender, SentFunds: sentFunds}
+	return b
+}
+
+// WithContract sets the address of the contract being executed
+func (b *EnvBuilder) WithContract(address HumanAddress) *EnvBuilder {
+	b.env.Contract = ContractInfo{Address: address}
+	return b
+}
+
+// Build returns the Env, or an error naming the first required field that is missing
+func (b *EnvBuilder) Build() (Env, error) {
+	switch {
+	case b.env.Block.Height == 0:
+		return Env{}, fmt.Errorf("env: missing block height")
+	case b.env.Block.ChainID == "":
+		return Env{}, fmt.Errorf("env: missing chain id")
+	case b.env.Message.Sender == "":
+		return Env{}, fmt.Errorf("env: missing message sender")
+	case b.env.Contract.Address == "":
+		return Env{}, fmt.Errorf("env: missing contract address")
+	}
+	env := b.env
+	if env.Message.SentFunds == nil {
+		env.Message.SentFunds = Coins{}
+	}
+	return env, nil
+}
@@ -47,3 +47,25 @@ func TestMessageInfoHandlesMissingCoins(t *testing.T) {
 	require.True(t, ok)
 	assert.Equal(t, string(sent), "[]")
 }
+
+func TestEnvBuilder(t *testing.T) {
+	env, err := NewEnvBuilder().
+		WithBlock(123, 1578939743, "testing").
+		WithMessage("sender", nil).
+		WithContract("contract").
+		Build()
+	require.NoError(t, err)
+	assert.Equal(t, Env{
+		Block:    BlockInfo{Height: 123, Time: 1578939743, ChainID: "testing"},
+		Message:  MessageInfo{Sender: "sender", SentFunds: Coins{}},
+		Contract: ContractInfo{Address: "contract"},
+	}, env)
+
+	_, err = NewEnvBuilder().WithBlock(123, 0, "testing").WithContract("contract").Build()
+	require.Error(t, err)
+	assert.Equal(t, "env: missing message sender", err.Error())
+
+	_, err = NewEnvBuilder().WithMessage("sender", nil).WithContract("contract").Build()
+	require.Error(t, err)
+	assert.Equal(t, "env: missing block height", err.Error())
+}
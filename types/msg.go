@@ -20,6 +20,8 @@ type HandleResponse struct {
 	Data []byte `json:"data"`
 	// log message to return over abci interface
 	Log []LogAttribute `json:"log"`
+	// custom events emitted in addition to the log, if the contract supports them
+	Events []Event `json:"events,omitempty"`
 }
 
 // InitResult is the raw response from the handle call
@@ -32,8 +34,12 @@ type InitResult struct {
 type InitResponse struct {
 	// Messages comes directly from the contract and is it's request for action
 	Messages []CosmosMsg `json:"messages"`
+	// base64-encoded bytes to return as ABCI.Data field
+	Data []byte `json:"data"`
 	// log message to return over abci interface
 	Log []LogAttribute `json:"log"`
+	// custom events emitted in addition to the log, if the contract supports them
+	Events []Event `json:"events,omitempty"`
 }
 
 // MigrateResult is the raw response from the handle call
@@ -50,6 +56,8 @@ type MigrateResponse struct {
 	Data []byte `json:"data"`
 	// log message to return over abci interface
 	Log []LogAttribute `json:"log"`
+	// custom events emitted in addition to the log, if the contract supports them
+	Events []Event `json:"events,omitempty"`
 }
 
 // LogAttribute
@@ -58,6 +66,12 @@ type LogAttribute struct {
 	Value string `json:"value"`
 }
 
+// Event is a custom event emitted by a contract, which the chain can emit as an abci event
+type Event struct {
+	Type       string         `json:"type"`
+	Attributes []LogAttribute `json:"attributes"`
+}
+
 // CosmosMsg is an rust enum and only (exactly) one of the fields should be set
 // Should we do a cleaner approach in Go? (type/data?)
 type CosmosMsg struct {
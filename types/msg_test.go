@@ -0,0 +1,64 @@
+package types
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestInitResultWithData(t *testing.T) {
+	// this is how a factory contract returns the address of the contract it created
+	raw := []byte(`{"Ok":{"messages":[],"data":"AQID","log":[{"key":"action","value":"init"}]}}`)
+
+	var res InitResult
+	err := json.Unmarshal(raw, &res)
+	require.NoError(t, err)
+	require.Nil(t, res.Err)
+	require.NotNil(t, res.Ok)
+	assert.Equal(t, []byte{1, 2, 3}, res.Ok.Data)
+	assert.Equal(t, []LogAttribute{{Key: "action", Value: "init"}}, res.Ok.Log)
+
+	// and it survives a round trip
+	bz, err := json.Marshal(res)
+	require.NoError(t, err)
+	var recover InitResult
+	err = json.Unmarshal(bz, &recover)
+	require.NoError(t, err)
+	assert.Equal(t, res, recover)
+}
+
+func TestInitResultWithoutData(t *testing.T) {
+	raw := []byte(`{"Ok":{"messages":[],"data":null,"log":[]}}`)
+
+	var res InitResult
+	err := json.Unmarshal(raw, &res)
+	require.NoError(t, err)
+	require.NotNil(t, res.Ok)
+	assert.Nil(t, res.Ok.Data)
+}
+
+func TestHandleResultWithEvents(t *testing.T) {
+	raw := []byte(`{"Ok":{"messages":[],"data":null,"log":[{"key":"action","value":"transfer"}],"events":[{"type":"hackatom","attributes":[{"key":"sender","value":"fred"}]}]}}`)
+
+	var res HandleResult
+	err := json.Unmarshal(raw, &res)
+	require.NoError(t, err)
+	require.NotNil(t, res.Ok)
+	assert.Equal(t, []LogAttribute{{Key: "action", Value: "transfer"}}, res.Ok.Log)
+	assert.Equal(t, []Event{{
+		Type:       "hackatom",
+		Attributes: []LogAttribute{{Key: "sender", Value: "fred"}},
+	}}, res.Ok.Events)
+
+	// responses without events are unchanged
+	raw = []byte(`{"Ok":{"messages":[],"data":null,"log":[]}}`)
+	var plain HandleResult
+	err = json.Unmarshal(raw, &plain)
+	require.NoError(t, err)
+	assert.Nil(t, plain.Ok.Events)
+	bz, err := json.Marshal(plain)
+	require.NoError(t, err)
+	assert.Equal(t, string(raw), string(bz))
+}
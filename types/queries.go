@@ -11,6 +11,18 @@ type QueryResponse struct {
 	Err *StdError `json:"Err,omitempty"`
 }
 
+// QueryResultKind tells apart the outcomes of a contract query
+type QueryResultKind int
+
+const (
+	// QueryResultOk means the contract returned a result
+	QueryResultOk QueryResultKind = iota
+	// QueryResultContractError means the contract returned an error
+	QueryResultContractError
+	// QueryResultSystemError means the query failed outside of the contract, e.g. it ran out of gas
+	QueryResultSystemError
+)
+
 //-------- Querier -----------
 
 type Querier interface {
@@ -2,6 +2,8 @@ package types
 
 import (
 	"encoding/json"
+	"errors"
+	"fmt"
 	"strconv"
 )
 
@@ -50,6 +52,9 @@ func (c *Coins) UnmarshalJSON(data []byte) error {
 	return nil
 }
 
+// OutOfGasError is returned when a contract runs out of gas outside of a contract call.
+// Contract calls return ErrOutOfGasWasm or ErrOutOfGasHost instead, which are not equal to
+// OutOfGasError{} and cannot be type asserted to it. Use errors.Is(err, OutOfGasError{}) to match all of them.
 type OutOfGasError struct{}
 
 var _ error = OutOfGasError{}
@@ -57,3 +62,115 @@ var _ error = OutOfGasError{}
 func (o OutOfGasError) Error() string {
 	return "Out of gas"
 }
+
+// OutOfGasWasmError is the type of ErrOutOfGasWasm
+type OutOfGasWasmError struct{}
+
+func (o OutOfGasWasmError) Error() string {
+	return "Out of gas in wasm execution"
+}
+
+// Is makes errors.Is(err, OutOfGasError{}) match this error
+func (o OutOfGasWasmError) Is(target error) bool {
+	return target == OutOfGasError{}
+}
+
+// OutOfGasHostError is the type of ErrOutOfGasHost
+type OutOfGasHostError struct{}
+
+func (o OutOfGasHostError) Error() string {
+	return "Out of gas in host call"
+}
+
+// Is makes errors.Is(err, OutOfGasError{}) match this error
+func (o OutOfGasHostError) Is(target error) bool {
+	return target == OutOfGasError{}
+}
+
+// ErrOutOfGasWasm is returned when a contract reaches its gas limit inside of the VM.
+// Use errors.Is(err, OutOfGasError{}) to match it as well as ErrOutOfGasHost.
+var ErrOutOfGasWasm error = OutOfGasWasmError{}
+
+// ErrOutOfGasHost is returned when the host runs out of gas on behalf of a contract,
+// e.g. a storage callback exhausting the sdk gas meter.
+// Use errors.Is(err, OutOfGasError{}) to match it as well as ErrOutOfGasWasm.
+//
+// The VM only reports that a call ran out of gas, so the two are told apart by the gas used:
+// a call stopping below its gas limit is assumed to have run out of gas in a callback.
+// This is a heuristic, as a callback may also run out of gas exactly at the limit.
+var ErrOutOfGasHost error = OutOfGasHostError{}
+
+// ErrHealthCheckDisabled is returned by HealthCheck unless VMConfig.EnableHealthCheck is set
+var ErrHealthCheckDisabled = errors.New("health check is disabled")
+
+// ErrCodeNotFound is returned when there is no code stored for a code id
+var ErrCodeNotFound = errors.New("code not found")
+
+// ErrInvalidChecksum is returned when a code id passed to the VM is not a valid checksum
+var ErrInvalidChecksum = errors.New("invalid checksum")
+
+// ErrCodeTooLarge is returned when code read by CreateFromReader exceeds VMConfig.MaxCodeSize
+var ErrCodeTooLarge = errors.New("code exceeds max size")
+
+// ErrMessageTooComplex is returned when a json input exceeds the configured complexity limits
+var ErrMessageTooComplex = errors.New("message exceeds complexity limits")
+
+// ErrMessageTooLarge is returned when the msg of an entry point exceeds the configured max size
+var ErrMessageTooLarge = errors.New("message exceeds max size")
+
+// ErrMsgNotObject is returned when the msg passed to instantiate, execute or migrate is not a json object
+var ErrMsgNotObject = errors.New("msg must be a JSON object")
+
+// ErrKeyNotAllowed is returned when a contract accesses a key outside of VMConfig.AllowedKeyPrefixes
+var ErrKeyNotAllowed = errors.New("key outside of the allowed prefixes")
+
+// ErrNoMigrateEntryPoint is returned when migrating to a code that does not export migrate
+var ErrNoMigrateEntryPoint = errors.New("no migrate entry point")
+
+// ErrInvalidFunds is returned when the funds sent to instantiate or execute are not valid coins
+var ErrInvalidFunds = errors.New("invalid funds")
+
+// ErrResultTooLarge is returned when the result of a contract exceeds VMConfig.MaxResultSize
+var ErrResultTooLarge = errors.New("contract result exceeds max size")
+
+// ErrTooManyMessages is returned when a contract returns more messages than VMConfig.MaxMessages
+var ErrTooManyMessages = errors.New("contract returned too many messages")
+
+// ErrGasMeterNotChargeable is returned when VMConfig.GasConfig sets costs, but the gas meter passed to
+// an entry point implements neither WasmGasMeter nor SDKGasMeter, so the costs cannot be charged
+var ErrGasMeterNotChargeable = errors.New("gas meter can not be charged with the costs of the gas config")
+
+// ErrInvalidQueryResult is returned when a query result is not valid json and VMConfig.ValidateQueryResults is set
+var ErrInvalidQueryResult = errors.New("contract returned invalid JSON response")
+
+// ContractError is returned when the contract itself returned an error from instantiate, execute,
+// query or migrate. Use errors.As to inspect the error of the contract.
+type ContractError struct {
+	Err StdError
+}
+
+var _ error = ContractError{}
+
+func (e ContractError) Error() string {
+	return e.Err.Error()
+}
+
+// HostPanicError is returned when a host callback (store, api or querier) panicked during a contract call
+type HostPanicError struct {
+	// Value is the value the callback panicked with
+	Value interface{}
+	// Stack is the stack trace of the panic
+	Stack []byte
+}
+
+var _ error = HostPanicError{}
+
+func (e HostPanicError) Error() string {
+	return fmt.Sprintf("panic in host callback: %v", e.Value)
+}
+
+// Unwrap returns Value if the callback panicked with an error
+func (e HostPanicError) Unwrap() error {
+	err, _ := e.Value.(error)
+	return err
+}
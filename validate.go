@@ -0,0 +1,245 @@
+package cosmwasm
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/json"
+	"fmt"
+	"regexp"
+	"strings"
+
+	"github.com/CosmWasm/go-cosmwasm/types"
+)
+
+// validateChecksum ensures a CodeID has the length of the sha256 checksums the VM uses as code ids
+func validateChecksum(code CodeID) error {
+	if len(code) != sha256.Size {
+		return fmt.Errorf("%w: expected %d bytes, got %d", types.ErrInvalidChecksum, sha256.Size, len(code))
+	}
+	return nil
+}
+
+// checkMessageSize ensures the msg or serialized env of an entry point does not exceed the max size of the config
+func (w *Wasmer) checkMessageSize(msg []byte) error {
+	if max := w.config.MaxMessageSize; max > 0 && len(msg) > max {
+		return fmt.Errorf("%w: %d exceeds %d bytes", types.ErrMessageTooLarge, len(msg), max)
+	}
+	return nil
+}
+
+// checkJSONLimits ensures the json input does not exceed the size and depth limits of the config.
+// This only scans the bytes and does not parse them, so it is cheap to run before any other validation.
+func checkJSONLimits(config types.VMConfig, bz []byte) error {
+	if config.MaxJSONSize > 0 && len(bz) > config.MaxJSONSize {
+		return fmt.Errorf("%w: size %d exceeds %d bytes", types.ErrMessageTooComplex, len(bz), config.MaxJSONSize)
+	}
+	if config.MaxJSONDepth > 0 && exceedsJSONDepth(bz, config.MaxJSONDepth) {
+		return fmt.Errorf("%w: nesting exceeds depth %d", types.ErrMessageTooComplex, config.MaxJSONDepth)
+	}
+	return nil
+}
+
+// exceedsJSONDepth returns true if objects or arrays in bz are nested deeper than max.
+// Brackets inside of strings are ignored.
+func exceedsJSONDepth(bz []byte, max int) bool {
+	depth := 0
+	inString, escaped := false, false
+	for _, b := range bz {
+		if inString {
+			switch {
+			case escaped:
+				escaped = false
+			case b == '\\':
+				escaped = true
+			case b == '"':
+				inString = false
+			}
+			continue
+		}
+		switch b {
+		case '"':
+			inString = true
+		case '{', '[':
+			depth++
+			if depth > max {
+				return true
+			}
+		case '}', ']':
+			depth--
+		}
+	}
+	return false
+}
+
+// checkWasm runs static checks on wasm code before it is passed to the VM for compilation
+func (w *Wasmer) checkWasm(code []byte) error {
+	module, err := parseWasm(code)
+	if err != nil {
+		return err
+	}
+	// every cosmwasm contract needs these to exchange data with the VM
+	if !module.hasExport("allocate", externFunc) || !module.hasExport("deallocate", externFunc) {
+		return fmt.Errorf("contract missing allocate/deallocate export")
+	}
+	memories := module.allMemories()
+	if len(memories) != 1 {
+		return fmt.Errorf("expected exactly one memory, found %d", len(memories))
+	}
+	if max := w.config.MaxInitialMemoryPages; max > 0 {
+		for _, mem := range memories {
+			if mem.Min > max {
+				return fmt.Errorf("contract requests too much initial memory: %d pages exceeds %d", mem.Min, max)
+			}
+		}
+	}
+	return nil
+}
+
+// checkFeatures ensures the VM supports all features required by the code
+func (w *Wasmer) checkFeatures(code CodeID) error {
+	report, err := w.AnalyzeCode(code)
+	if err != nil {
+		return err
+	}
+	supported := make(map[string]bool)
+	for _, f := range splitFeatures(w.config.SupportedFeatures) {
+		supported[f] = true
+	}
+	var missing []string
+	for _, f := range report.RequiredFeaturesSet {
+		if !supported[f] {
+			missing = append(missing, f)
+		}
+	}
+	if len(missing) > 0 {
+		return fmt.Errorf("contract requires unavailable features: %s", strings.Join(missing, ","))
+	}
+	return nil
+}
+
+// checkMigrate ensures the code a contract is migrated to exports migrate
+func (w *Wasmer) checkMigrate(code CodeID) error {
+	info, err := w.GetCodeInfo(code)
+	if err != nil {
+		return err
+	}
+	if !info.HasMigrateEntryPoint {
+		return fmt.Errorf("target code %X has %w", code, types.ErrNoMigrateEntryPoint)
+	}
+	return nil
+}
+
+// splitFeatures splits a comma separated list of features, ignoring whitespace and empty entries
+func splitFeatures(features string) []string {
+	var res []string
+	for _, f := range strings.Split(features, ",") {
+		if f = strings.TrimSpace(f); f != "" {
+			res = append(res, f)
+		}
+	}
+	return res
+}
+
+// checkInputs runs all checks on the inputs of an entry point before they are passed to the VM.
+// It returns the gas charged for this work, which must be deducted from the gas limit of the call.
+// If the checks fail, the charged gas is already consumed on the gas meter.
+func (w *Wasmer) checkInputs(gasMeter GasMeter, gasLimit uint64, inputs ...[]byte) (uint64, error) {
+	var size uint64
+	for _, input := range inputs {
+		size += uint64(len(input))
+	}
+	perByte := w.config.InputGasPerByte
+	if perByte > 0 && size > gasLimit/perByte {
+		chargeWasmGas(gasMeter, gasMeter.GasConsumed(), gasLimit)
+		return gasLimit, types.ErrOutOfGasHost
+	}
+	gas := size * perByte
+
+	for _, input := range inputs {
+		if err := checkJSONLimits(w.config, input); err != nil {
+			chargeWasmGas(gasMeter, gasMeter.GasConsumed(), gas)
+			return gas, err
+		}
+	}
+	return gas, nil
+}
+
+// maxReportedBytes is the number of bytes of invalid json included in errors
+const maxReportedBytes = 64
+
+// quoteTruncated quotes bz for an error message, truncated to maxReportedBytes
+func quoteTruncated(bz []byte) string {
+	if len(bz) > maxReportedBytes {
+		return fmt.Sprintf("%q...", bz[:maxReportedBytes])
+	}
+	return fmt.Sprintf("%q", bz)
+}
+
+// checkMsgObject ensures the msg of a state changing entry point starts like a json object,
+// which is what contracts expect to deserialize. It only looks at the first byte, so it is cheap to
+// run before any gas is charged. checkMsgInputs validates the whole msg later.
+func checkMsgObject(msg []byte) error {
+	trimmed := bytes.TrimLeft(msg, " \t\r\n")
+	if len(trimmed) == 0 || trimmed[0] != '{' {
+		return fmt.Errorf("%w: %s", types.ErrMsgNotObject, quoteTruncated(msg))
+	}
+	return nil
+}
+
+// checkMsgInputs is checkInputs for the entry points taking an env and a json object msg.
+// The msg is only parsed once gas was charged for it and it passed the limits of checkInputs.
+func (w *Wasmer) checkMsgInputs(gasMeter GasMeter, gasLimit uint64, env, msg []byte) (uint64, error) {
+	gas, err := w.checkInputs(gasMeter, gasLimit, env, msg)
+	if err != nil {
+		return gas, err
+	}
+	if !json.Valid(msg) {
+		chargeWasmGas(gasMeter, gasMeter.GasConsumed(), gas)
+		return gas, fmt.Errorf("%w: %s", types.ErrMsgNotObject, quoteTruncated(msg))
+	}
+	return gas, nil
+}
+
+// denomRegex matches the coin denoms of the cosmos-sdk, including ibc denoms
+var denomRegex = regexp.MustCompile(`^[a-zA-Z][a-zA-Z0-9/:._-]{2,127}$`)
+
+// amountRegex matches non-negative integer amounts
+var amountRegex = regexp.MustCompile(`^[0-9]+$`)
+
+// checkFunds ensures all coins sent along with a message have a valid denom and amount
+func checkFunds(funds types.Coins) error {
+	for _, coin := range funds {
+		if !denomRegex.MatchString(coin.Denom) {
+			return fmt.Errorf("%w: invalid denom %q", types.ErrInvalidFunds, coin.Denom)
+		}
+		if !amountRegex.MatchString(coin.Amount) {
+			return fmt.Errorf("%w: invalid amount %q of %s", types.ErrInvalidFunds, coin.Amount, coin.Denom)
+		}
+	}
+	return nil
+}
+
+// checkResultSize ensures the serialized result of a contract does not exceed the max size of the config.
+// The result has already been copied out of the VM at this point, but it is not decoded any further.
+func (w *Wasmer) checkResultSize(data []byte) error {
+	if max := w.config.MaxResultSize; max > 0 && len(data) > max {
+		return fmt.Errorf("%w: %d exceeds %d bytes", types.ErrResultTooLarge, len(data), max)
+	}
+	return nil
+}
+
+// checkMessageCount ensures a contract response does not exceed the max number of messages of the config
+func (w *Wasmer) checkMessageCount(msgs []types.CosmosMsg) error {
+	if max := w.config.MaxMessages; max > 0 && len(msgs) > max {
+		return fmt.Errorf("%w: %d exceeds %d", types.ErrTooManyMessages, len(msgs), max)
+	}
+	return nil
+}
+
+// checkQueryResult ensures a non-empty query result is valid json, if enabled in the config
+func (w *Wasmer) checkQueryResult(res []byte) error {
+	if !w.config.ValidateQueryResults || len(res) == 0 || json.Valid(res) {
+		return nil
+	}
+	return fmt.Errorf("%w: %s", types.ErrInvalidQueryResult, quoteTruncated(res))
+}
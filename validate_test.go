@@ -0,0 +1,186 @@
+package cosmwasm
+
+import (
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/CosmWasm/go-cosmwasm/types"
+)
+
+func TestCheckJSONLimits(t *testing.T) {
+	config := types.VMConfig{MaxJSONDepth: 3, MaxJSONSize: 64}
+	deep := strings.Repeat("[", 4) + strings.Repeat("]", 4)
+
+	cases := map[string]struct {
+		input []byte
+		valid bool
+	}{
+		"flat object":             {[]byte(`{"release":{}}`), true},
+		"max depth":               {[]byte(`{"a":[{"b":1}]}`), true},
+		"too deep":                {[]byte(deep), false},
+		"brackets in strings":     {[]byte(`{"a":"[[[[{{{{"}`), true},
+		"escaped quote in string": {[]byte(`{"a":"\"[[[["}`), true},
+		"too big":                 {[]byte(`{"a":"` + strings.Repeat("x", 64) + `"}`), false},
+	}
+	for name, tc := range cases {
+		t.Run(name, func(t *testing.T) {
+			err := checkJSONLimits(config, tc.input)
+			if tc.valid {
+				assert.NoError(t, err)
+			} else {
+				require.Error(t, err)
+				assert.True(t, errors.Is(err, types.ErrMessageTooComplex))
+			}
+		})
+	}
+
+	// zero values disable the checks
+	assert.NoError(t, checkJSONLimits(types.VMConfig{}, []byte(deep)))
+}
+
+func TestCheckMessageSize(t *testing.T) {
+	w := &Wasmer{config: types.VMConfig{MaxMessageSize: 8}}
+	assert.NoError(t, w.checkMessageSize([]byte(`{"a":1}`)))
+	err := w.checkMessageSize([]byte(`{"a":"foo"}`))
+	assert.True(t, errors.Is(err, types.ErrMessageTooLarge))
+	assert.Equal(t, "message exceeds max size: 11 exceeds 8 bytes", err.Error())
+
+	// 0 means unlimited
+	w = &Wasmer{}
+	assert.NoError(t, w.checkMessageSize(make([]byte, 1<<20)))
+}
+
+func TestCheckInputsChargesGas(t *testing.T) {
+	w := &Wasmer{config: types.VMConfig{InputGasPerByte: 10, MaxJSONDepth: 1}}
+	sdk := &sdkMeter{}
+	meter := NewMultipliedGasMeter(sdk, 1)
+
+	gas, err := w.checkInputs(meter, 1000, []byte(`{}`), []byte(`{"a":1}`))
+	require.NoError(t, err)
+	assert.Equal(t, uint64(90), gas)
+	// successful checks are charged together with the contract call
+	assert.Equal(t, uint64(0), sdk.consumed)
+
+	// not enough gas to even check the inputs
+	gas, err = w.checkInputs(meter, 40, []byte(`{"a":1}`))
+	assert.Equal(t, types.ErrOutOfGasHost, err)
+	assert.Equal(t, uint64(40), gas)
+	assert.Equal(t, uint64(40), sdk.consumed)
+
+	// failed checks are charged right away
+	gas, err = w.checkInputs(meter, 1000, []byte(`[[]]`))
+	assert.True(t, errors.Is(err, types.ErrMessageTooComplex))
+	assert.Equal(t, uint64(40), gas)
+	assert.Equal(t, uint64(80), sdk.consumed)
+}
+
+func TestSplitFeatures(t *testing.T) {
+	assert.Nil(t, splitFeatures(""))
+	assert.Equal(t, []string{"staking"}, splitFeatures("staking"))
+	assert.Equal(t, []string{"iterator", "staking"}, splitFeatures(" iterator,, staking "))
+}
+
+func TestCheckQueryResult(t *testing.T) {
+	w := &Wasmer{config: types.VMConfig{ValidateQueryResults: true}}
+	assert.NoError(t, w.checkQueryResult([]byte(`{"verifier":"fred"}`)))
+	assert.NoError(t, w.checkQueryResult(nil))
+
+	err := w.checkQueryResult([]byte(`{"verifier":`))
+	assert.True(t, errors.Is(err, types.ErrInvalidQueryResult))
+	assert.Equal(t, `contract returned invalid JSON response: "{\"verifier\":"`, err.Error())
+
+	// long results are truncated
+	err = w.checkQueryResult([]byte(strings.Repeat("x", 100)))
+	assert.Equal(t, `contract returned invalid JSON response: "`+strings.Repeat("x", 64)+`"...`, err.Error())
+
+	// off by default
+	w = &Wasmer{}
+	assert.NoError(t, w.checkQueryResult([]byte(`{"verifier":`)))
+}
+
+func TestCheckMsgObject(t *testing.T) {
+	assert.NoError(t, checkMsgObject([]byte(`{}`)))
+	assert.NoError(t, checkMsgObject([]byte(` {"release":{}}`)))
+
+	// only the first byte is checked here, see checkMsgInputs
+	assert.NoError(t, checkMsgObject([]byte(`{"release":`)))
+
+	for _, msg := range []string{``, `[]`, `"release"`, ` `} {
+		err := checkMsgObject([]byte(msg))
+		assert.True(t, errors.Is(err, types.ErrMsgNotObject), "msg: %q", msg)
+	}
+	err := checkMsgObject([]byte(`[1,2]`))
+	assert.Equal(t, `msg must be a JSON object: "[1,2]"`, err.Error())
+}
+
+func TestCheckMsgInputs(t *testing.T) {
+	w := &Wasmer{config: types.VMConfig{InputGasPerByte: 10, MaxJSONDepth: 3}}
+	sdk := &sdkMeter{}
+	meter := NewMultipliedGasMeter(sdk, 1)
+	id := Checksum([]byte("unknown"))
+	env := mockEnv("creator")
+
+	// invalid json is charged like any failed check
+	gas, err := w.checkMsgInputs(meter, 1000, []byte(`{}`), []byte(`{"release":`))
+	assert.True(t, errors.Is(err, types.ErrMsgNotObject))
+	assert.Equal(t, uint64(130), gas)
+	assert.Equal(t, uint64(130), sdk.consumed)
+
+	// a large invalid msg is not parsed without the gas to check it
+	sdk.consumed = 0
+	large := []byte(`{"a":"` + strings.Repeat("x", 10000))
+	_, gasUsed, err := w.Execute(id, env, large, newMemStore(), mockAPI(), nil, meter, 1000)
+	assert.Equal(t, types.ErrOutOfGasHost, err)
+	assert.Equal(t, uint64(1000), gasUsed)
+	assert.Equal(t, uint64(1000), sdk.consumed)
+
+	// a too deep msg fails the depth limit before it is parsed
+	deep := []byte(`{"a":` + strings.Repeat("[", 10))
+	_, _, err = w.Execute(id, env, deep, newMemStore(), mockAPI(), nil, meter, 100000)
+	assert.True(t, errors.Is(err, types.ErrMessageTooComplex))
+}
+
+func TestCheckFunds(t *testing.T) {
+	assert.NoError(t, checkFunds(nil))
+	assert.NoError(t, checkFunds(types.Coins{types.NewCoin(0, "uatom"), {Denom: "ibc/27394FB092D2ECCD56123C74F36E4C1F926001CEADA9CA97EA622B25F41E5EB2", Amount: "12345678901234567890123"}}))
+
+	for _, coin := range []types.Coin{
+		{Denom: "", Amount: "1"},
+		{Denom: "1atom", Amount: "1"},
+		{Denom: "uatom", Amount: "-1"},
+		{Denom: "uatom", Amount: ""},
+		{Denom: "uatom", Amount: "1.5"},
+	} {
+		err := checkFunds(types.Coins{types.NewCoin(1, "ustake"), coin})
+		assert.True(t, errors.Is(err, types.ErrInvalidFunds), "coin: %v", coin)
+	}
+	err := checkFunds(types.Coins{{Denom: "uatom", Amount: "-1"}})
+	assert.Equal(t, `invalid funds: invalid amount "-1" of uatom`, err.Error())
+}
+
+func TestCheckMessageCount(t *testing.T) {
+	w := &Wasmer{config: types.VMConfig{MaxMessages: 2}}
+	assert.NoError(t, w.checkMessageCount(nil))
+	assert.NoError(t, w.checkMessageCount(make([]types.CosmosMsg, 2)))
+	err := w.checkMessageCount(make([]types.CosmosMsg, 3))
+	assert.True(t, errors.Is(err, types.ErrTooManyMessages))
+	assert.Equal(t, "contract returned too many messages: 3 exceeds 2", err.Error())
+
+	// 0 means unlimited
+	w = &Wasmer{}
+	assert.NoError(t, w.checkMessageCount(make([]types.CosmosMsg, 1000)))
+}
+
+func TestValidateChecksum(t *testing.T) {
+	assert.NoError(t, validateChecksum(Checksum([]byte("foo"))))
+	for _, code := range []CodeID{nil, {}, CodeID("foobar"), make(CodeID, 33)} {
+		err := validateChecksum(code)
+		assert.True(t, errors.Is(err, types.ErrInvalidChecksum))
+	}
+	err := validateChecksum(CodeID("foobar"))
+	assert.Equal(t, "invalid checksum: expected 32 bytes, got 6", err.Error())
+}
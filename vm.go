@@ -0,0 +1,91 @@
+package cosmwasm
+
+import (
+	"io"
+
+	"github.com/CosmWasm/go-cosmwasm/types"
+)
+
+// VM is the interface of the contract runtime. Wasmer is the implementation backed by the Rust VM.
+// Depend on it to substitute fakes in tests or alternative backends.
+// It covers storing, inspecting and calling code. The configuration, logging and metrics of a Wasmer
+// and the JSON variants of the entry points (which only encode the msg) are not part of it.
+type VM interface {
+	Create(code WasmCode) (CodeID, error)
+	CreateFromReader(r io.Reader) (CodeID, error)
+	GetCode(code CodeID) (WasmCode, error)
+	WriteCodeTo(code CodeID, out io.Writer) (int, error)
+	GetCodeInfo(code CodeID) (types.CodeInfo, error)
+	AnalyzeCode(code CodeID) (*types.AnalysisReport, error)
+	DescribeExports(code CodeID) ([]types.FunctionSignature, error)
+	Instantiate(
+		code CodeID,
+		env types.Env,
+		initMsg []byte,
+		store KVStore,
+		goapi GoAPI,
+		querier Querier,
+		gasMeter GasMeter,
+		gasLimit uint64,
+	) (*types.InitResponse, uint64, error)
+	Execute(
+		code CodeID,
+		env types.Env,
+		executeMsg []byte,
+		store KVStore,
+		goapi GoAPI,
+		querier Querier,
+		gasMeter GasMeter,
+		gasLimit uint64,
+	) (*types.HandleResponse, uint64, error)
+	Query(
+		code CodeID,
+		queryMsg []byte,
+		store KVStore,
+		goapi GoAPI,
+		querier Querier,
+		gasMeter GasMeter,
+		gasLimit uint64,
+	) ([]byte, uint64, error)
+	QueryRaw(
+		code CodeID,
+		queryMsg []byte,
+		store KVStore,
+		goapi GoAPI,
+		querier Querier,
+		gasMeter GasMeter,
+		gasLimit uint64,
+	) ([]byte, types.QueryResultKind, uint64, error)
+	Migrate(
+		code CodeID,
+		env types.Env,
+		migrateMsg []byte,
+		store KVStore,
+		goapi GoAPI,
+		querier Querier,
+		gasMeter GasMeter,
+		gasLimit uint64,
+	) (*types.MigrateResponse, uint64, error)
+	ExecuteMultiple(
+		steps []ExecuteStep,
+		store KVStore,
+		goapi GoAPI,
+		querier Querier,
+		gasMeter GasMeter,
+		gasLimit uint64,
+	) ([]ExecuteStepResult, uint64, error)
+	SimulateExecute(
+		code CodeID,
+		env types.Env,
+		executeMsg []byte,
+		store KVStore,
+		goapi GoAPI,
+		querier Querier,
+		gasMeter GasMeter,
+		gasLimit uint64,
+	) (uint64, error)
+	HealthCheck() error
+	Cleanup()
+}
+
+var _ VM = (*Wasmer)(nil)
@@ -0,0 +1,396 @@
+package cosmwasm
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+)
+
+// This is a minimal parser for the wasm binary format. It only reads the parts of
+// a module we need to give good errors before handing the code to the VM.
+// https://webassembly.github.io/spec/core/binary/modules.html
+
+var wasmMagic = []byte{0x00, 0x61, 0x73, 0x6d, 0x01, 0x00, 0x00, 0x00}
+
+const (
+	sectionCustom   byte = 0
+	sectionType     byte = 1
+	sectionImport   byte = 2
+	sectionFunction byte = 3
+	sectionMemory   byte = 5
+	sectionExport   byte = 7
+	sectionCode     byte = 10
+	sectionData     byte = 11
+)
+
+// valueTypes maps the binary encoding of value types to their text format names
+var valueTypes = map[byte]string{
+	0x7f: "i32",
+	0x7e: "i64",
+	0x7d: "f32",
+	0x7c: "f64",
+	0x7b: "v128",
+	0x70: "funcref",
+	0x6f: "externref",
+}
+
+type funcType struct {
+	Params  []string
+	Results []string
+}
+
+type externKind byte
+
+const (
+	externFunc   externKind = 0
+	externTable  externKind = 1
+	externMemory externKind = 2
+	externGlobal externKind = 3
+)
+
+type wasmExport struct {
+	Name  string
+	Kind  externKind
+	Index uint32
+}
+
+// wasmMemory holds the limits of a memory in pages of 64 KiB. Max is nil if unbounded.
+type wasmMemory struct {
+	Min uint32
+	Max *uint32
+}
+
+type wasmImport struct {
+	Module string
+	Name   string
+	Kind   externKind
+	// TypeIndex is the index into the type section for imported functions
+	TypeIndex uint32
+	// Memory holds the limits of imported memories
+	Memory wasmMemory
+}
+
+type wasmModule struct {
+	types   []funcType
+	imports []wasmImport
+	// funcs holds the type index of every function defined in the module
+	funcs    []uint32
+	memories []wasmMemory
+	exports  []wasmExport
+	// customSections holds the names of all custom sections
+	customSections []string
+}
+
+var errUnexpectedEnd = errors.New("unexpected end of data")
+
+// parseWasm reads the sections of a wasm module we are interested in and skips the others
+func parseWasm(code []byte) (*wasmModule, error) {
+	if !bytes.HasPrefix(code, wasmMagic) {
+		return nil, fmt.Errorf("invalid wasm: bad magic number or version")
+	}
+	r := wasmReader{data: code, pos: len(wasmMagic)}
+	var module wasmModule
+	for !r.done() {
+		id, err := r.readByte()
+		if err != nil {
+			return nil, err
+		}
+		size, err := r.readU32()
+		if err != nil {
+			return nil, err
+		}
+		content, err := r.readBytes(int(size))
+		if err != nil {
+			return nil, err
+		}
+		section := wasmReader{data: content}
+		switch id {
+		case sectionCustom:
+			var name string
+			if name, err = section.readName(); err == nil {
+				module.customSections = append(module.customSections, name)
+			}
+		case sectionType:
+			module.types, err = section.readTypes()
+		case sectionImport:
+			module.imports, err = section.readImports()
+		case sectionFunction:
+			module.funcs, err = section.readVecU32()
+		case sectionMemory:
+			module.memories, err = section.readMemories()
+		case sectionExport:
+			module.exports, err = section.readExports()
+		}
+		if err != nil {
+			return nil, fmt.Errorf("invalid wasm: section %d: %w", id, err)
+		}
+	}
+	return &module, nil
+}
+
+// hasExport returns true if the module exports name with the given kind
+func (m *wasmModule) hasExport(name string, kind externKind) bool {
+	for _, e := range m.exports {
+		if e.Name == name && e.Kind == kind {
+			return true
+		}
+	}
+	return false
+}
+
+// funcType returns the type of the function with the given index, counting imported functions first
+func (m *wasmModule) funcType(index uint32) (funcType, error) {
+	typeIndex := uint32(0)
+	found := false
+	for _, imp := range m.imports {
+		if imp.Kind != externFunc {
+			continue
+		}
+		if index == 0 {
+			typeIndex, found = imp.TypeIndex, true
+			break
+		}
+		index--
+	}
+	if !found {
+		if int(index) >= len(m.funcs) {
+			return funcType{}, fmt.Errorf("function index out of range")
+		}
+		typeIndex = m.funcs[index]
+	}
+	if int(typeIndex) >= len(m.types) {
+		return funcType{}, fmt.Errorf("type index %d out of range", typeIndex)
+	}
+	return m.types[typeIndex], nil
+}
+
+// allMemories returns the imported memories followed by the ones defined in the module
+func (m *wasmModule) allMemories() []wasmMemory {
+	var res []wasmMemory
+	for _, imp := range m.imports {
+		if imp.Kind == externMemory {
+			res = append(res, imp.Memory)
+		}
+	}
+	return append(res, m.memories...)
+}
+
+type wasmReader struct {
+	data []byte
+	pos  int
+}
+
+func (r *wasmReader) done() bool {
+	return r.pos >= len(r.data)
+}
+
+func (r *wasmReader) readByte() (byte, error) {
+	if r.done() {
+		return 0, errUnexpectedEnd
+	}
+	b := r.data[r.pos]
+	r.pos++
+	return b, nil
+}
+
+func (r *wasmReader) readBytes(n int) ([]byte, error) {
+	if n < 0 || len(r.data)-r.pos < n {
+		return nil, errUnexpectedEnd
+	}
+	res := r.data[r.pos : r.pos+n]
+	r.pos += n
+	return res, nil
+}
+
+// readU32 reads an unsigned LEB128 encoded integer of at most 32 bits
+func (r *wasmReader) readU32() (uint32, error) {
+	var res uint32
+	for shift := uint(0); shift < 35; shift += 7 {
+		b, err := r.readByte()
+		if err != nil {
+			return 0, err
+		}
+		res |= uint32(b&0x7f) << shift
+		if b&0x80 == 0 {
+			return res, nil
+		}
+	}
+	return 0, errors.New("integer representation too long")
+}
+
+func (r *wasmReader) readName() (string, error) {
+	n, err := r.readU32()
+	if err != nil {
+		return "", err
+	}
+	bz, err := r.readBytes(int(n))
+	if err != nil {
+		return "", err
+	}
+	return string(bz), nil
+}
+
+func (r *wasmReader) readExports() ([]wasmExport, error) {
+	count, err := r.readU32()
+	if err != nil {
+		return nil, err
+	}
+	exports := make([]wasmExport, 0, count)
+	for i := uint32(0); i < count; i++ {
+		name, err := r.readName()
+		if err != nil {
+			return nil, err
+		}
+		kind, err := r.readByte()
+		if err != nil {
+			return nil, err
+		}
+		index, err := r.readU32()
+		if err != nil {
+			return nil, err
+		}
+		exports = append(exports, wasmExport{Name: name, Kind: externKind(kind), Index: index})
+	}
+	return exports, nil
+}
+
+func (r *wasmReader) readVecU32() ([]uint32, error) {
+	count, err := r.readU32()
+	if err != nil {
+		return nil, err
+	}
+	res := make([]uint32, 0, count)
+	for i := uint32(0); i < count; i++ {
+		v, err := r.readU32()
+		if err != nil {
+			return nil, err
+		}
+		res = append(res, v)
+	}
+	return res, nil
+}
+
+func (r *wasmReader) readValueTypes() ([]string, error) {
+	count, err := r.readU32()
+	if err != nil {
+		return nil, err
+	}
+	res := make([]string, 0, count)
+	for i := uint32(0); i < count; i++ {
+		b, err := r.readByte()
+		if err != nil {
+			return nil, err
+		}
+		name, ok := valueTypes[b]
+		if !ok {
+			return nil, fmt.Errorf("unknown value type 0x%x", b)
+		}
+		res = append(res, name)
+	}
+	return res, nil
+}
+
+func (r *wasmReader) readTypes() ([]funcType, error) {
+	count, err := r.readU32()
+	if err != nil {
+		return nil, err
+	}
+	res := make([]funcType, 0, count)
+	for i := uint32(0); i < count; i++ {
+		form, err := r.readByte()
+		if err != nil {
+			return nil, err
+		}
+		if form != 0x60 {
+			return nil, fmt.Errorf("unknown type form 0x%x", form)
+		}
+		params, err := r.readValueTypes()
+		if err != nil {
+			return nil, err
+		}
+		results, err := r.readValueTypes()
+		if err != nil {
+			return nil, err
+		}
+		res = append(res, funcType{Params: params, Results: results})
+	}
+	return res, nil
+}
+
+// readLimits reads the limits of a table or memory
+func (r *wasmReader) readLimits() (min uint32, max *uint32, err error) {
+	flags, err := r.readByte()
+	if err != nil {
+		return 0, nil, err
+	}
+	min, err = r.readU32()
+	if err != nil {
+		return 0, nil, err
+	}
+	if flags&1 != 0 {
+		m, err := r.readU32()
+		if err != nil {
+			return 0, nil, err
+		}
+		max = &m
+	}
+	return min, max, nil
+}
+
+func (r *wasmReader) readMemories() ([]wasmMemory, error) {
+	count, err := r.readU32()
+	if err != nil {
+		return nil, err
+	}
+	res := make([]wasmMemory, 0, count)
+	for i := uint32(0); i < count; i++ {
+		min, max, err := r.readLimits()
+		if err != nil {
+			return nil, err
+		}
+		res = append(res, wasmMemory{Min: min, Max: max})
+	}
+	return res, nil
+}
+
+func (r *wasmReader) readImports() ([]wasmImport, error) {
+	count, err := r.readU32()
+	if err != nil {
+		return nil, err
+	}
+	res := make([]wasmImport, 0, count)
+	for i := uint32(0); i < count; i++ {
+		module, err := r.readName()
+		if err != nil {
+			return nil, err
+		}
+		name, err := r.readName()
+		if err != nil {
+			return nil, err
+		}
+		kind, err := r.readByte()
+		if err != nil {
+			return nil, err
+		}
+		imp := wasmImport{Module: module, Name: name, Kind: externKind(kind)}
+		switch imp.Kind {
+		case externFunc:
+			imp.TypeIndex, err = r.readU32()
+		case externTable:
+			if _, err = r.readByte(); err == nil {
+				_, _, err = r.readLimits()
+			}
+		case externMemory:
+			imp.Memory.Min, imp.Memory.Max, err = r.readLimits()
+		case externGlobal:
+			_, err = r.readBytes(2)
+		default:
+			err = fmt.Errorf("unknown import kind 0x%x", kind)
+		}
+		if err != nil {
+			return nil, err
+		}
+		res = append(res, imp)
+	}
+	return res, nil
+}
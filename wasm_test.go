@@ -0,0 +1,136 @@
+package cosmwasm
+
+import (
+	"io/ioutil"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/CosmWasm/go-cosmwasm/types"
+)
+
+// buildWasm assembles a module from the given raw sections (id followed by content)
+func buildWasm(sections ...[]byte) []byte {
+	code := append([]byte{}, wasmMagic...)
+	for _, s := range sections {
+		code = append(code, s[0], byte(len(s)-1))
+		code = append(code, s[1:]...)
+	}
+	return code
+}
+
+// exportSection creates an export section exporting the given names as functions 0..n
+func exportSection(memory bool, funcs ...string) []byte {
+	count := len(funcs)
+	if memory {
+		count++
+	}
+	s := []byte{sectionExport, byte(count)}
+	if memory {
+		s = append(s, 6, 'm', 'e', 'm', 'o', 'r', 'y', byte(externMemory), 0)
+	}
+	for i, name := range funcs {
+		s = append(s, byte(len(name)))
+		s = append(s, name...)
+		s = append(s, byte(externFunc), byte(i))
+	}
+	return s
+}
+
+func TestParseWasm(t *testing.T) {
+	code, err := ioutil.ReadFile("./api/testdata/hackatom.wasm")
+	require.NoError(t, err)
+
+	module, err := parseWasm(code)
+	require.NoError(t, err)
+	assert.True(t, module.hasExport("memory", externMemory))
+	assert.True(t, module.hasExport("allocate", externFunc))
+	assert.True(t, module.hasExport("cosmwasm_vm_version_3", externFunc))
+	assert.False(t, module.hasExport("memory", externFunc))
+	assert.False(t, module.hasExport("foobar", externFunc))
+}
+
+func TestParseWasmErrors(t *testing.T) {
+	_, err := parseWasm([]byte("some invalid data"))
+	assert.Error(t, err)
+
+	// section length larger than the remaining data
+	code := append(append([]byte{}, wasmMagic...), sectionExport, 20, 1)
+	_, err = parseWasm(code)
+	assert.Error(t, err)
+
+	// an empty module is fine
+	module, err := parseWasm(buildWasm())
+	require.NoError(t, err)
+	assert.Empty(t, module.exports)
+}
+
+func TestCheckWasm(t *testing.T) {
+	w := &Wasmer{}
+
+	code, err := ioutil.ReadFile("./api/testdata/hackatom.wasm")
+	require.NoError(t, err)
+	assert.NoError(t, w.checkWasm(code))
+
+	code = buildWasm(exportSection(true, "allocate", "query"))
+	err = w.checkWasm(code)
+	require.Error(t, err)
+	assert.Equal(t, "contract missing allocate/deallocate export", err.Error())
+}
+
+// memorySection creates a memory section with one memory per given minimum, without maximum
+func memorySection(mins ...byte) []byte {
+	s := []byte{sectionMemory, byte(len(mins))}
+	for _, min := range mins {
+		s = append(s, 0, min)
+	}
+	return s
+}
+
+func TestParseMemories(t *testing.T) {
+	module, err := parseWasm(buildWasm(memorySection(17)))
+	require.NoError(t, err)
+	assert.Equal(t, []wasmMemory{{Min: 17}}, module.allMemories())
+
+	max := uint32(32)
+	module, err = parseWasm(buildWasm([]byte{sectionMemory, 1, 1, 2, 32}))
+	require.NoError(t, err)
+	assert.Equal(t, []wasmMemory{{Min: 2, Max: &max}}, module.allMemories())
+
+	// imported memories come first
+	imports := []byte{sectionImport, 1, 3, 'e', 'n', 'v', 3, 'm', 'e', 'm', byte(externMemory), 0, 4}
+	module, err = parseWasm(buildWasm(imports, memorySection(1)))
+	require.NoError(t, err)
+	assert.Equal(t, []wasmMemory{{Min: 4}, {Min: 1}}, module.allMemories())
+}
+
+func TestCheckWasmMemoryCount(t *testing.T) {
+	w := &Wasmer{}
+	exports := exportSection(true, "allocate", "deallocate")
+
+	err := w.checkWasm(buildWasm(exports))
+	require.Error(t, err)
+	assert.Equal(t, "expected exactly one memory, found 0", err.Error())
+
+	err = w.checkWasm(buildWasm(memorySection(1, 1), exports))
+	require.Error(t, err)
+	assert.Equal(t, "expected exactly one memory, found 2", err.Error())
+}
+
+func TestCheckWasmInitialMemory(t *testing.T) {
+	w := &Wasmer{config: types.VMConfig{MaxInitialMemoryPages: 16}}
+	exports := exportSection(true, "allocate", "deallocate")
+
+	assert.NoError(t, w.checkWasm(buildWasm(memorySection(16), exports)))
+	err := w.checkWasm(buildWasm(memorySection(17), exports))
+	require.Error(t, err)
+	assert.Equal(t, "contract requests too much initial memory: 17 pages exceeds 16", err.Error())
+
+	// hackatom needs 17 pages
+	code, err := ioutil.ReadFile("./api/testdata/hackatom.wasm")
+	require.NoError(t, err)
+	assert.Error(t, w.checkWasm(code))
+	w.config.MaxInitialMemoryPages = 17
+	assert.NoError(t, w.checkWasm(code))
+}